@@ -0,0 +1,538 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"mcp-ai-client/internal/mcp"
+	"mcp-ai-client/internal/service"
+	"os"
+	"strings"
+	"time"
+)
+
+// errUsage 命令行参数错误，退出码为2
+var errUsage = errors.New("参数错误")
+
+// exitCode 根据错误类型返回进程退出码
+func exitCode(err error) int {
+	switch {
+	case err == nil, errors.Is(err, flag.ErrHelp):
+		return 0
+	case errors.Is(err, errUsage):
+		return 2
+	default:
+		return 1
+	}
+}
+
+// parseArgs 解析选项并返回位置参数，允许选项和位置参数交替出现
+func parseArgs(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// toolOptions AI工具子命令的通用选项
+type toolOptions struct {
+	configPath string
+	json       bool
+	verbose    bool
+	timeout    time.Duration
+	provider   string
+	model      string
+}
+
+// newToolFlagSet 创建AI工具子命令的选项集，timeout 为默认超时（与HTTP接口一致）
+func newToolFlagSet(name, operand string, timeout time.Duration, opts *toolOptions) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.StringVar(&opts.configPath, "config", defaultConfigPath, "配置文件路径")
+	fs.BoolVar(&opts.json, "json", false, "以JSON输出（与HTTP接口响应一致）")
+	fs.BoolVar(&opts.verbose, "v", false, "输出运行日志")
+	fs.DurationVar(&opts.timeout, "timeout", timeout, "调用超时时间")
+	fs.StringVar(&opts.provider, "provider", "", "AI提供商，默认使用配置中的 default_provider")
+	fs.StringVar(&opts.model, "model", "", "AI模型，默认使用配置中的 default_model")
+	fs.Usage = func() {
+		if operand != "" {
+			fmt.Fprintf(fs.Output(), "用法: mcp-ai-client %s [选项] \"%s\"\n", name, operand)
+		} else {
+			fmt.Fprintf(fs.Output(), "用法: mcp-ai-client %s [选项]\n", name)
+		}
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parseOperand 解析选项，位置参数合并为工具的主输入（prompt、instruction 或 description）
+func parseOperand(fs *flag.FlagSet, args []string, operand string) (string, error) {
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return "", err
+	}
+	text := strings.TrimSpace(strings.Join(positional, " "))
+	if text == "" {
+		fs.Usage()
+		return "", fmt.Errorf("%w: 缺少 %s", errUsage, operand)
+	}
+	return text, nil
+}
+
+// toolCall 一次AI工具调用：参数构建和结果整理与HTTP处理器共用 AIToolService
+type toolCall struct {
+	tool     string
+	timeout  time.Duration
+	args     func(s *service.AIToolService) map[string]interface{}
+	response func(s *service.AIToolService, result *mcp.ToolCallResult, start time.Time) map[string]interface{}
+}
+
+// toolSession 命令行使用的MCP连接
+type toolSession struct {
+	client *mcp.MCPClient
+	tools  *service.AIToolService
+}
+
+// openToolSession 加载配置并完成MCP握手
+func openToolSession(opts *toolOptions) (*toolSession, error) {
+	if !opts.verbose {
+		log.SetOutput(io.Discard)
+	}
+
+	config, err := loadConfig(opts.configPath, scopeMCP)
+	if err != nil {
+		return nil, fmt.Errorf("加载配置失败: %v", err)
+	}
+	if opts.verbose {
+		if _, err := setupLogger(config); err != nil {
+			return nil, err
+		}
+	}
+
+	client, err := connectMCP(config, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return &toolSession{
+		client: client,
+		tools:  service.NewAIToolService(config.AI.AIToolConfig),
+	}, nil
+}
+
+// call 调用工具并返回与HTTP接口一致的响应数据
+func (s *toolSession) call(call toolCall, timeout time.Duration) (map[string]interface{}, error) {
+	if timeout <= 0 {
+		timeout = call.timeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	start := time.Now()
+	result, err := s.client.CallTool(ctx, call.tool, call.args(s.tools))
+	if err != nil {
+		return nil, err
+	}
+	return call.response(s.tools, result, start), nil
+}
+
+// runToolCommand 执行单个AI工具子命令
+func runToolCommand(opts *toolOptions, call toolCall) error {
+	session, err := openToolSession(opts)
+	if err != nil {
+		return err
+	}
+	defer session.client.Close()
+
+	data, err := session.call(call, opts.timeout)
+	if err != nil {
+		if opts.json {
+			printJSON(errorData(call.tool, err))
+		}
+		return err
+	}
+
+	if opts.json {
+		printJSON(data)
+	} else {
+		printResponse(data)
+	}
+	return nil
+}
+
+// ===== AI工具子命令 (5.1-5.5) =====
+
+// runChat 5.1 AI对话
+func runChat(args []string) error {
+	var opts toolOptions
+	var request service.ChatRequest
+	fs := newToolFlagSet("chat", "<prompt>", 60*time.Second, &opts)
+	fs.IntVar(&request.MaxTokens, "max-tokens", 0, "最大生成token数")
+	fs.Float64Var(&request.Temperature, "temperature", 0, "采样温度")
+
+	prompt, err := parseOperand(fs, args, "prompt")
+	if err != nil {
+		return err
+	}
+	request.Prompt = prompt
+	request.Provider, request.Model = opts.provider, opts.model
+
+	return runToolCommand(&opts, chatCall(request))
+}
+
+// runFileManager 5.2 AI文件管理
+func runFileManager(args []string) error {
+	var opts toolOptions
+	var request service.FileManagerRequest
+	fs := newToolFlagSet("file", "<instruction>", 90*time.Second, &opts)
+	fs.StringVar(&request.TargetPath, "target-path", "", "目标路径，相对路径基于当前目录")
+	fs.StringVar(&request.OperationMode, "operation-mode", "", "操作模式，如 plan、execute")
+
+	instruction, err := parseOperand(fs, args, "instruction")
+	if err != nil {
+		return err
+	}
+	request.Instruction = instruction
+	request.Provider, request.Model = opts.provider, opts.model
+
+	return runToolCommand(&opts, fileManagerCall(request))
+}
+
+// runDataProcessor 5.3 AI数据处理
+func runDataProcessor(args []string) error {
+	var opts toolOptions
+	var request service.DataProcessorRequest
+	var input string
+	fs := newToolFlagSet("data", "<instruction>", 90*time.Second, &opts)
+	fs.StringVar(&input, "input", "", "待处理数据（必填）；- 表示从标准输入读取，@file 表示从文件读取")
+	fs.StringVar(&request.DataType, "data-type", "", "数据类型，如 json、csv、text")
+	fs.StringVar(&request.OutputFormat, "output-format", "", "输出格式，如 json、table")
+	fs.StringVar(&request.OperationMode, "operation-mode", "", "操作模式，如 plan、execute")
+
+	instruction, err := parseOperand(fs, args, "instruction")
+	if err != nil {
+		return err
+	}
+	if request.InputData, err = readInput(input); err != nil {
+		return err
+	}
+	if request.InputData == "" {
+		fs.Usage()
+		return fmt.Errorf("%w: 缺少 -input", errUsage)
+	}
+	request.Instruction = instruction
+	request.Provider, request.Model = opts.provider, opts.model
+
+	return runToolCommand(&opts, dataProcessorCall(request))
+}
+
+// runAPIClient 5.4 AI网络请求
+func runAPIClient(args []string) error {
+	var opts toolOptions
+	var request service.APIClientRequest
+	fs := newToolFlagSet("api", "<instruction>", 90*time.Second, &opts)
+	fs.StringVar(&request.BaseURL, "base-url", "", "接口基础地址")
+	fs.StringVar(&request.AuthInfo, "auth-info", "", "认证信息，如 Bearer token")
+	fs.StringVar(&request.RequestMode, "request-mode", "", "请求模式，如 plan、execute")
+	fs.BoolVar(&request.ResponseAnalysis, "response-analysis", false, "是否分析响应内容")
+
+	instruction, err := parseOperand(fs, args, "instruction")
+	if err != nil {
+		return err
+	}
+	request.Instruction = instruction
+	request.Provider, request.Model = opts.provider, opts.model
+
+	return runToolCommand(&opts, apiClientCall(request))
+}
+
+// runQueryWithAnalysis 5.5 AI数据库查询
+func runQueryWithAnalysis(args []string) error {
+	var opts toolOptions
+	var request service.QueryWithAnalysisRequest
+	fs := newToolFlagSet("db", "<description>", 120*time.Second, &opts)
+	fs.StringVar(&request.AnalysisType, "analysis-type", "", "分析类型，如 summary、insights")
+	fs.StringVar(&request.TableName, "table-name", "", "查询的表名")
+	fs.StringVar(&request.Context, "context", "", "补充的业务背景")
+	fs.StringVar(&request.InsightLevel, "insight-level", "", "洞察深度")
+
+	description, err := parseOperand(fs, args, "description")
+	if err != nil {
+		return err
+	}
+	request.Description = description
+	request.Provider, request.Model = opts.provider, opts.model
+
+	return runToolCommand(&opts, queryWithAnalysisCall(request))
+}
+
+// runDemo 依次调用5个AI工具，单个工具失败不影响后续演示
+func runDemo(args []string) error {
+	var opts toolOptions
+	fs := newToolFlagSet("demo", "", 0, &opts)
+	fs.Lookup("timeout").Usage = "每个工具的调用超时时间，默认使用各工具自己的超时"
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) > 0 {
+		fs.Usage()
+		return fmt.Errorf("%w: demo 不接受位置参数", errUsage)
+	}
+
+	session, err := openToolSession(&opts)
+	if err != nil {
+		return err
+	}
+	defer session.client.Close()
+
+	calls := []toolCall{
+		chatCall(service.ChatRequest{
+			Prompt:   "你好，请介绍一下MCP协议是什么？50字以内。",
+			Provider: opts.provider,
+			Model:    opts.model,
+		}),
+		fileManagerCall(service.FileManagerRequest{
+			Instruction:   "创建一个Go项目的标准目录结构",
+			TargetPath:    "./demo-go-project",
+			OperationMode: "plan",
+			Provider:      opts.provider,
+			Model:         opts.model,
+		}),
+		dataProcessorCall(service.DataProcessorRequest{
+			Instruction:   "解析这个JSON数据并提取所有用户的邮箱地址",
+			InputData:     `{"users":[{"name":"张三","email":"zhangsan@example.com","age":25},{"name":"李四","email":"lisi@example.com","age":30}]}`,
+			DataType:      "json",
+			OutputFormat:  "table",
+			OperationMode: "execute",
+			Provider:      opts.provider,
+			Model:         opts.model,
+		}),
+		apiClientCall(service.APIClientRequest{
+			Instruction:      "获取用户数据",
+			BaseURL:          "https://jsonplaceholder.typicode.com",
+			RequestMode:      "execute",
+			ResponseAnalysis: true,
+			Provider:         opts.provider,
+			Model:            opts.model,
+		}),
+		queryWithAnalysisCall(service.QueryWithAnalysisRequest{
+			Description:  "查询所有员工信息",
+			AnalysisType: "insights",
+			TableName:    "mcp_user",
+			Provider:     opts.provider,
+			Model:        opts.model,
+		}),
+	}
+
+	var results []map[string]interface{}
+	failed := 0
+	for i, call := range calls {
+		if !opts.json {
+			fmt.Printf("\n===== [%d/%d] %s =====\n", i+1, len(calls), call.tool)
+		}
+		data, err := session.call(call, opts.timeout)
+		if err != nil {
+			failed++
+			data = errorData(call.tool, err)
+			if !opts.json {
+				fmt.Fprintf(os.Stderr, "❌ %s 调用失败: %v\n", call.tool, err)
+			}
+		} else if !opts.json {
+			printResponse(data)
+		}
+		results = append(results, data)
+	}
+
+	if opts.json {
+		printJSON(results)
+	} else {
+		fmt.Printf("\n演示完成: %d 个成功, %d 个失败\n", len(calls)-failed, failed)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d 个工具调用失败", failed)
+	}
+	return nil
+}
+
+// ===== 工具调用定义 =====
+
+func chatCall(request service.ChatRequest) toolCall {
+	return toolCall{
+		tool:    "ai_chat",
+		timeout: 60 * time.Second,
+		args: func(s *service.AIToolService) map[string]interface{} {
+			return s.ChatArgs(request)
+		},
+		response: func(s *service.AIToolService, result *mcp.ToolCallResult, start time.Time) map[string]interface{} {
+			return s.ChatResponse(request, result, start)
+		},
+	}
+}
+
+func fileManagerCall(request service.FileManagerRequest) toolCall {
+	return toolCall{
+		tool:    "ai_file_manager",
+		timeout: 90 * time.Second,
+		args: func(s *service.AIToolService) map[string]interface{} {
+			return s.FileManagerArgs(request)
+		},
+		response: func(s *service.AIToolService, result *mcp.ToolCallResult, start time.Time) map[string]interface{} {
+			return s.InstructionResponse("ai_file_manager", request.Instruction, result, start)
+		},
+	}
+}
+
+func dataProcessorCall(request service.DataProcessorRequest) toolCall {
+	return toolCall{
+		tool:    "ai_data_processor",
+		timeout: 90 * time.Second,
+		args: func(s *service.AIToolService) map[string]interface{} {
+			return s.DataProcessorArgs(request)
+		},
+		response: func(s *service.AIToolService, result *mcp.ToolCallResult, start time.Time) map[string]interface{} {
+			return s.InstructionResponse("ai_data_processor", request.Instruction, result, start)
+		},
+	}
+}
+
+func apiClientCall(request service.APIClientRequest) toolCall {
+	return toolCall{
+		tool:    "ai_api_client",
+		timeout: 90 * time.Second,
+		args: func(s *service.AIToolService) map[string]interface{} {
+			return s.APIClientArgs(request)
+		},
+		response: func(s *service.AIToolService, result *mcp.ToolCallResult, start time.Time) map[string]interface{} {
+			return s.InstructionResponse("ai_api_client", request.Instruction, result, start)
+		},
+	}
+}
+
+func queryWithAnalysisCall(request service.QueryWithAnalysisRequest) toolCall {
+	return toolCall{
+		tool:    "ai_query_with_analysis",
+		timeout: 120 * time.Second,
+		args: func(s *service.AIToolService) map[string]interface{} {
+			return s.QueryWithAnalysisArgs(request)
+		},
+		response: func(s *service.AIToolService, result *mcp.ToolCallResult, start time.Time) map[string]interface{} {
+			return s.QueryWithAnalysisResponse(request, result, start)
+		},
+	}
+}
+
+// ===== 输出 =====
+
+// readInput 读取 -input 参数：- 为标准输入，@file 为文件内容，其余原样返回
+func readInput(input string) (string, error) {
+	switch {
+	case input == "-":
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return "", fmt.Errorf("读取标准输入失败: %v", err)
+		}
+		return string(data), nil
+	case strings.HasPrefix(input, "@"):
+		data, err := os.ReadFile(input[1:])
+		if err != nil {
+			return "", fmt.Errorf("读取输入文件失败: %v", err)
+		}
+		return string(data), nil
+	default:
+		return input, nil
+	}
+}
+
+// errorData 与HTTP接口错误响应一致的错误信息
+func errorData(toolName string, err error) map[string]interface{} {
+	data := map[string]interface{}{
+		"tool":        toolName,
+		"status":      "error",
+		"error":       err.Error(),
+		"error_class": mcp.ClassifyError(err),
+	}
+	var toolErr *mcp.ToolError
+	if errors.As(err, &toolErr) && toolErr.Result != nil {
+		data["result"] = toolErr.Result
+	}
+	return data
+}
+
+// printJSON 以缩进JSON输出到标准输出
+func printJSON(v interface{}) {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(v)
+}
+
+// printResponse 以可读格式输出调用结果：正文写到标准输出，耗时等附加信息写到标准错误
+func printResponse(data map[string]interface{}) {
+	for _, key := range []string{"response", "result"} {
+		if value, ok := data[key]; ok {
+			printValue(value)
+			break
+		}
+	}
+	sections := []struct{ key, title string }{
+		{"analysis", "分析"},
+		{"insights", "洞察"},
+	}
+	for _, section := range sections {
+		if value, ok := data[section.key].(string); ok && value != "" {
+			fmt.Printf("\n── %s ──\n", section.title)
+			fmt.Println(value)
+		}
+	}
+
+	if attachments, ok := data["attachments"].([]map[string]interface{}); ok {
+		for _, attachment := range attachments {
+			fmt.Printf("📎 %s\n", describeAttachment(attachment))
+		}
+	}
+
+	meta := []string{fmt.Sprint(data["tool"])}
+	if provider, _ := data["provider"].(string); provider != "" {
+		model, _ := data["model"].(string)
+		meta = append(meta, provider+"/"+model)
+	}
+	if duration, _ := data["duration"].(string); duration != "" {
+		meta = append(meta, duration)
+	}
+	fmt.Fprintf(os.Stderr, "✅ %s\n", strings.Join(meta, " · "))
+}
+
+// printValue 字符串原样输出，其他值输出为缩进JSON
+func printValue(value interface{}) {
+	if text, ok := value.(string); ok {
+		fmt.Println(text)
+		return
+	}
+	printJSON(value)
+}
+
+// describeAttachment 附件的单行描述
+func describeAttachment(attachment map[string]interface{}) string {
+	parts := []string{fmt.Sprint(attachment["type"])}
+	for _, key := range []string{"mime_type", "name", "uri"} {
+		if value, _ := attachment[key].(string); value != "" {
+			parts = append(parts, value)
+		}
+	}
+	if data, _ := attachment["data"].(string); data != "" {
+		parts = append(parts, fmt.Sprintf("%d 字节(base64)", len(data)))
+	}
+	return strings.Join(parts, " ")
+}
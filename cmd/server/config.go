@@ -0,0 +1,414 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"mcp-ai-client/internal/database"
+	"mcp-ai-client/internal/logging"
+	"mcp-ai-client/internal/mcp"
+	"mcp-ai-client/internal/service"
+	"mcp-ai-client/internal/tracing"
+	"net/url"
+	"os"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// envPrefix 环境变量覆盖配置的前缀，变量名由 yaml 路径转大写并以下划线连接，
+// 例如 database.mysql.password 对应 MCP_AI_CLIENT_DATABASE_MYSQL_PASSWORD
+const envPrefix = "MCP_AI_CLIENT"
+
+// defaultConfigPath 默认配置文件路径，可通过 MCP_AI_CLIENT_CONFIG 修改
+var defaultConfigPath = envOrDefault(envPrefix+"_CONFIG", "configs/config.yaml")
+
+// Config 配置结构
+type Config struct {
+	Server struct {
+		Port            int           `yaml:"port"`
+		Host            string        `yaml:"host"`
+		WatchConfig     bool          `yaml:"watch_config"`     // 配置文件变化时自动重新加载（SIGHUP 始终可用）
+		ShutdownTimeout time.Duration `yaml:"shutdown_timeout"` // 关闭时等待处理中请求的时间，默认30s
+	} `yaml:"server"`
+	Database struct {
+		MySQL  database.MySQLConfig `yaml:"mysql"`
+		Tables struct {
+			UserTable string `yaml:"user_table"`
+		} `yaml:"tables"`
+		History struct {
+			Enabled bool `yaml:"enabled"`
+		} `yaml:"history"`
+		Migrations struct {
+			Auto bool `yaml:"auto"`
+		} `yaml:"migrations"`
+	} `yaml:"database"`
+	MCP struct {
+		Transport string              `yaml:"transport"`
+		ServerURL string              `yaml:"server_url"`
+		Stdio     mcp.StdioConfig     `yaml:"stdio"`
+		HTTP      mcp.HTTPConfig      `yaml:"http"`
+		Timeout   time.Duration       `yaml:"timeout"`
+		Reconnect mcp.ReconnectConfig `yaml:"reconnect"`
+		Database  struct {
+			Alias  string `yaml:"alias"`
+			Driver string `yaml:"driver"`
+			DSN    string `yaml:"dsn"`
+		} `yaml:"database"`
+	} `yaml:"mcp"`
+	AI struct {
+		service.AIToolConfig `yaml:",inline"`
+		Conversation         service.ConversationConfig `yaml:"conversation"`
+	} `yaml:"ai"`
+	Log     logging.Config `yaml:"log"`
+	Tracing tracing.Config `yaml:"tracing"`
+}
+
+// configScope 命令需要校验的配置部分，只连接MCP的命令不要求MySQL配置完整
+type configScope int
+
+const (
+	scopeServer configScope = 1 << iota
+	scopeDatabase
+	scopeMCP
+)
+
+// ConfigError 配置校验失败，包含全部问题
+type ConfigError struct {
+	Problems []string
+}
+
+// Error 实现 error 接口
+func (e *ConfigError) Error() string {
+	return "配置无效:\n  - " + strings.Join(e.Problems, "\n  - ")
+}
+
+// add 记录一个配置问题
+func (e *ConfigError) add(format string, a ...interface{}) {
+	e.Problems = append(e.Problems, fmt.Sprintf(format, a...))
+}
+
+// loadConfig 加载配置文件
+// 依次处理：YAML 中的 ${VAR} / ${VAR:-默认值} 插值、MCP_AI_CLIENT_* 环境变量覆盖、默认值，
+// 最后按 scope 校验；所有问题汇总后一次返回
+func loadConfig(configPath string, scope configScope) (*Config, error) {
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("读取配置文件失败: %v", err)
+	}
+
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("解析配置文件 %s 失败: %v", configPath, err)
+	}
+
+	problems := &ConfigError{}
+	interpolateNode(&root, problems)
+
+	var config Config
+	if len(root.Content) > 0 {
+		if err := root.Decode(&config); err != nil {
+			return nil, fmt.Errorf("解析配置文件 %s 失败: %v", configPath, err)
+		}
+	}
+
+	if overridden := applyEnvOverrides(&config, problems); len(overridden) > 0 {
+		// 只记录变量名，值可能是密码
+		slog.Info("环境变量覆盖配置", "variables", overridden)
+	}
+
+	config.applyDefaults()
+	config.validate(scope, problems)
+	if len(problems.Problems) > 0 {
+		return nil, problems
+	}
+	return &config, nil
+}
+
+// ===== ${VAR} 插值 =====
+
+// envPattern 匹配 ${VAR}、${VAR:-默认值} 和转义形式 $${VAR}
+var envPattern = regexp.MustCompile(`\$(\$)?\{([A-Za-z_][A-Za-z0-9_]*)(?::-([^}]*))?\}`)
+
+// interpolateNode 替换所有标量值中的环境变量引用，$${VAR} 输出字面量 ${VAR}
+// 在解析后的节点上替换，变量值中的冒号、井号等字符不会破坏 YAML 结构，其中的 ${...} 也不会再次展开
+func interpolateNode(node *yaml.Node, problems *ConfigError) {
+	if node.Kind == yaml.ScalarNode {
+		node.Value = envPattern.ReplaceAllStringFunc(node.Value, func(ref string) string {
+			match := envPattern.FindStringSubmatch(ref)
+			if match[1] != "" {
+				return ref[1:]
+			}
+			if value, ok := os.LookupEnv(match[2]); ok {
+				return value
+			}
+			if strings.Contains(ref, ":-") {
+				return match[3]
+			}
+			problems.add("第 %d 行引用的环境变量 %s 未设置（可写为 ${%s:-默认值}）", node.Line, match[2], match[2])
+			return ""
+		})
+		return
+	}
+	for _, child := range node.Content {
+		interpolateNode(child, problems)
+	}
+}
+
+// ===== 环境变量覆盖 =====
+
+// applyEnvOverrides 按 yaml 路径用环境变量覆盖配置字段，返回生效的变量名
+// 切片支持JSON数组或逗号分隔，map 使用JSON对象，时长使用 Go duration 格式（如 30s）
+func applyEnvOverrides(config *Config, problems *ConfigError) []string {
+	var overridden []string
+	walkConfig(reflect.ValueOf(config).Elem(), "", func(path string, field reflect.Value) {
+		env := envPrefix + "_" + strings.ToUpper(strings.ReplaceAll(path, ".", "_"))
+		raw, ok := os.LookupEnv(env)
+		if !ok {
+			return
+		}
+		if err := setFromEnv(field, raw); err != nil {
+			problems.add("环境变量 %s (%s): %v", env, path, err)
+			return
+		}
+		overridden = append(overridden, env)
+	})
+	return overridden
+}
+
+// walkConfig 按 yaml 路径（如 database.mysql.password）遍历配置的叶子字段，inline 字段展开到上一层
+func walkConfig(v reflect.Value, path string, fn func(path string, field reflect.Value)) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, opts, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		if name == "-" || !field.IsExported() {
+			continue
+		}
+		if opts == "inline" {
+			walkConfig(v.Field(i), path, fn)
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+
+		fieldPath := strings.TrimPrefix(path+"."+name, ".")
+		if field.Type.Kind() == reflect.Struct && field.Type != reflect.TypeOf(time.Duration(0)) {
+			walkConfig(v.Field(i), fieldPath, fn)
+			continue
+		}
+		fn(fieldPath, v.Field(i))
+	}
+}
+
+// setFromEnv 将环境变量的字符串值写入字段
+func setFromEnv(field reflect.Value, raw string) error {
+	if field.Type() == reflect.TypeOf(time.Duration(0)) {
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return fmt.Errorf("应为时长，如 30s、1m: %q", raw)
+		}
+		field.SetInt(int64(d))
+		return nil
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("应为 true 或 false: %q", raw)
+		}
+		field.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return fmt.Errorf("应为整数: %q", raw)
+		}
+		field.SetInt(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return fmt.Errorf("应为数字: %q", raw)
+		}
+		field.SetFloat(f)
+	case reflect.Slice:
+		list := reflect.New(field.Type())
+		if strings.HasPrefix(strings.TrimSpace(raw), "[") {
+			if err := json.Unmarshal([]byte(raw), list.Interface()); err != nil {
+				return fmt.Errorf("应为JSON数组: %v", err)
+			}
+		} else if field.Type().Elem().Kind() == reflect.String {
+			for _, item := range strings.Split(raw, ",") {
+				if item = strings.TrimSpace(item); item != "" {
+					list.Elem().Set(reflect.Append(list.Elem(), reflect.ValueOf(item)))
+				}
+			}
+		} else {
+			return fmt.Errorf("应为JSON数组: %q", raw)
+		}
+		field.Set(list.Elem())
+	case reflect.Map:
+		m := reflect.New(field.Type())
+		if err := json.Unmarshal([]byte(raw), m.Interface()); err != nil {
+			return fmt.Errorf("应为JSON对象: %v", err)
+		}
+		field.Set(m.Elem())
+	default:
+		return fmt.Errorf("不支持通过环境变量设置 %s 类型", field.Type())
+	}
+	return nil
+}
+
+// envOrDefault 读取环境变量，未设置时返回默认值
+func envOrDefault(name, def string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+	return def
+}
+
+// ===== 默认值与校验 =====
+
+// applyDefaults 填充可省略的配置
+func (c *Config) applyDefaults() {
+	if c.Database.MySQL.Charset == "" {
+		c.Database.MySQL.Charset = "utf8mb4"
+	}
+	if c.Database.MySQL.Loc == "" {
+		c.Database.MySQL.Loc = "Local"
+	}
+	if c.Database.Tables.UserTable == "" {
+		c.Database.Tables.UserTable = "mcp_user"
+	}
+	if c.Server.ShutdownTimeout == 0 {
+		c.Server.ShutdownTimeout = 30 * time.Second
+	}
+	if c.MCP.Timeout == 0 {
+		c.MCP.Timeout = 30 * time.Second
+	}
+	c.AI.AIToolConfig = c.AI.AIToolConfig.WithDefaults()
+	c.Log = c.Log.WithDefaults()
+	c.Tracing = c.Tracing.WithDefaults()
+}
+
+// validate 校验配置，问题记录到 problems
+func (c *Config) validate(scope configScope, problems *ConfigError) {
+	if scope&scopeServer != 0 {
+		checkPort(problems, "server.port", c.Server.Port)
+		if c.Server.ShutdownTimeout < 0 {
+			problems.add("server.shutdown_timeout: 不能为负数（当前 %s）", c.Server.ShutdownTimeout)
+		}
+
+		trace := c.Tracing
+		switch trace.Exporter {
+		case tracing.ExporterNone, tracing.ExporterStdout:
+		case tracing.ExporterOTLP:
+			checkRequired(problems, "tracing.otlp.endpoint", trace.OTLP.Endpoint)
+			if p := trace.OTLP.Protocol; p != tracing.ProtocolGRPC && p != tracing.ProtocolHTTP {
+				problems.add("tracing.otlp.protocol: 必须是 grpc 或 http（当前 %q）", p)
+			}
+		default:
+			problems.add("tracing.exporter: 必须是 none、stdout 或 otlp（当前 %q）", trace.Exporter)
+		}
+		if trace.SampleRatio < 0 || trace.SampleRatio > 1 {
+			problems.add("tracing.sample_ratio: 必须在 0-1 之间（当前 %g）", trace.SampleRatio)
+		}
+	}
+
+	if scope&scopeDatabase != 0 {
+		mysql := c.Database.MySQL
+		checkRequired(problems, "database.mysql.host", mysql.Host)
+		checkPort(problems, "database.mysql.port", mysql.Port)
+		checkRequired(problems, "database.mysql.username", mysql.Username)
+		checkRequired(problems, "database.mysql.database", mysql.Database)
+		if _, err := time.LoadLocation(mysql.Loc); err != nil {
+			problems.add("database.mysql.loc: 无效的时区 %q", mysql.Loc)
+		}
+	}
+
+	if scope&scopeMCP != 0 {
+		switch c.MCP.Transport {
+		case "", mcp.TransportWebSocket:
+			checkURL(problems, "mcp.server_url", c.MCP.ServerURL, "ws", "wss")
+		case mcp.TransportHTTP:
+			checkURL(problems, "mcp.server_url", c.MCP.ServerURL, "http", "https")
+		case mcp.TransportStdio:
+			checkRequired(problems, "mcp.stdio.command", c.MCP.Stdio.Command)
+		default:
+			problems.add("mcp.transport: 必须是 websocket、stdio 或 http（当前 %q）", c.MCP.Transport)
+		}
+		if c.MCP.Timeout < 0 {
+			problems.add("mcp.timeout: 不能为负数（当前 %s）", c.MCP.Timeout)
+		}
+		reconnect := c.MCP.Reconnect
+		if reconnect.InitialBackoff < 0 || reconnect.MaxBackoff < 0 {
+			problems.add("mcp.reconnect: initial_backoff 和 max_backoff 不能为负数")
+		}
+		if reconnect.MaxAttempts < 0 {
+			problems.add("mcp.reconnect.max_attempts: 不能为负数（当前 %d）", reconnect.MaxAttempts)
+		}
+		if p := reconnect.InflightPolicy; p != "" && p != mcp.InflightFail && p != mcp.InflightReplay {
+			problems.add("mcp.reconnect.inflight_policy: 必须是 fail 或 replay（当前 %q）", p)
+		}
+	}
+
+	switch c.AI.ResponseLanguage {
+	case "zh-CN", "en-US", "auto":
+	default:
+		problems.add("ai.response_language: 必须是 zh-CN、en-US 或 auto（当前 %q）", c.AI.ResponseLanguage)
+	}
+	conv := c.AI.Conversation
+	if conv.MaxTurns < 0 || conv.MaxTokens < 0 || conv.MaxConversations < 0 {
+		problems.add("ai.conversation: max_turns、max_tokens、max_conversations 不能为负数")
+	}
+
+	if _, err := logging.ParseLevel(c.Log.Level); err != nil {
+		problems.add("log.level: 必须是 debug、info、warn 或 error（当前 %q）", c.Log.Level)
+	}
+	if f := c.Log.Format; f != logging.FormatText && f != logging.FormatJSON {
+		problems.add("log.format: 必须是 text 或 json（当前 %q）", f)
+	}
+	if c.Log.MaxPayload < 0 {
+		problems.add("log.max_payload: 不能为负数（当前 %d）", c.Log.MaxPayload)
+	}
+}
+
+// checkRequired 检查必填项
+func checkRequired(problems *ConfigError, path, value string) {
+	if strings.TrimSpace(value) == "" {
+		problems.add("%s: 不能为空", path)
+	}
+}
+
+// checkPort 检查端口范围
+func checkPort(problems *ConfigError, path string, port int) {
+	if port < 1 || port > 65535 {
+		problems.add("%s: 必须在 1-65535 之间（当前 %d）", path, port)
+	}
+}
+
+// checkURL 检查地址格式和协议
+func checkURL(problems *ConfigError, path, raw string, schemes ...string) {
+	if raw == "" {
+		problems.add("%s: 不能为空", path)
+		return
+	}
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		problems.add("%s: 无效的地址 %q", path, raw)
+		return
+	}
+	for _, scheme := range schemes {
+		if u.Scheme == scheme {
+			return
+		}
+	}
+	problems.add("%s: 协议必须是 %s（当前 %q）", path, strings.Join(schemes, " 或 "), u.Scheme)
+}
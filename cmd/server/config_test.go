@@ -0,0 +1,222 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// baseConfig 通过全部校验的最小配置
+const baseConfig = `
+server:
+  port: 8080
+database:
+  mysql:
+    host: localhost
+    port: 3306
+    username: root
+    database: mcp_test
+mcp:
+  server_url: ws://localhost:8081
+`
+
+// writeConfig 把配置写入临时文件，返回文件路径
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("写入配置文件失败: %v", err)
+	}
+	return path
+}
+
+// configProblems 返回 loadConfig 的校验问题，加载成功时为 nil
+func configProblems(t *testing.T, content string) []string {
+	t.Helper()
+	_, err := loadConfig(writeConfig(t, content), scopeServer|scopeDatabase|scopeMCP)
+	if err == nil {
+		return nil
+	}
+	var configErr *ConfigError
+	if !errors.As(err, &configErr) {
+		t.Fatalf("loadConfig() error = %v, want *ConfigError", err)
+	}
+	return configErr.Problems
+}
+
+func TestInterpolateNode(t *testing.T) {
+	t.Setenv("CFG_TEST_HOST", "db.internal")
+	t.Setenv("CFG_TEST_PASSWORD", "p@ss:w#rd ${CFG_TEST_HOST}")
+	t.Setenv("CFG_TEST_EMPTY", "")
+
+	tests := []struct {
+		name        string
+		value       string
+		want        string
+		wantProblem string
+	}{
+		{name: "已设置的变量", value: "${CFG_TEST_HOST}", want: "db.internal"},
+		{name: "多个引用", value: "${CFG_TEST_HOST}:${CFG_TEST_PORT:-3306}", want: "db.internal:3306"},
+		{name: "默认值", value: "${CFG_TEST_UNSET:-root}", want: "root"},
+		{name: "空默认值", value: "x${CFG_TEST_UNSET:-}y", want: "xy"},
+		{name: "设置为空值时不使用默认值", value: "${CFG_TEST_EMPTY:-root}", want: ""},
+		{name: "变量值中的特殊字符和引用不再展开", value: "${CFG_TEST_PASSWORD}", want: "p@ss:w#rd ${CFG_TEST_HOST}"},
+		{name: "转义", value: "$${CFG_TEST_HOST}", want: "${CFG_TEST_HOST}"},
+		{name: "转义带默认值的引用", value: "a$${CFG_TEST_UNSET:-x}b", want: "a${CFG_TEST_UNSET:-x}b"},
+		{name: "不是引用的美元符号", value: "$HOME $5 ${}", want: "$HOME $5 ${}"},
+		{name: "未设置的变量", value: "${CFG_TEST_UNSET}", wantProblem: "CFG_TEST_UNSET 未设置"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var root yaml.Node
+			if err := yaml.Unmarshal([]byte("key: '"+tt.value+"'"), &root); err != nil {
+				t.Fatalf("解析YAML失败: %v", err)
+			}
+			problems := &ConfigError{}
+			interpolateNode(&root, problems)
+
+			if tt.wantProblem != "" {
+				if len(problems.Problems) != 1 || !strings.Contains(problems.Problems[0], tt.wantProblem) {
+					t.Errorf("problems = %q, want 包含 %q", problems.Problems, tt.wantProblem)
+				}
+				return
+			}
+			if len(problems.Problems) > 0 {
+				t.Fatalf("problems = %q", problems.Problems)
+			}
+			var got map[string]string
+			if err := root.Decode(&got); err != nil {
+				t.Fatalf("Decode() error = %v", err)
+			}
+			if got["key"] != tt.want {
+				t.Errorf("key = %q, want %q", got["key"], tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadConfigEnvOverrides(t *testing.T) {
+	path := writeConfig(t, baseConfig+`
+  timeout: 10s
+  stdio:
+    args: ["-v"]
+log:
+  level: info
+`)
+	t.Setenv("MCP_AI_CLIENT_SERVER_PORT", "9090")
+	t.Setenv("MCP_AI_CLIENT_MCP_SERVER_URL", "ws://override:9000")
+	t.Setenv("MCP_AI_CLIENT_MCP_TIMEOUT", "5s")
+	t.Setenv("MCP_AI_CLIENT_MCP_STDIO_ARGS", "a, b")
+	t.Setenv("MCP_AI_CLIENT_MCP_HTTP_HEADERS", `{"Authorization":"Bearer x"}`)
+	t.Setenv("MCP_AI_CLIENT_MCP_RECONNECT_REPLAY_TOOLS", `["search"]`)
+	t.Setenv("MCP_AI_CLIENT_DATABASE_MYSQL_PASSWORD", "secret")
+	t.Setenv("MCP_AI_CLIENT_LOG_LEVEL", "debug")
+
+	config, err := loadConfig(path, scopeServer|scopeDatabase|scopeMCP)
+	if err != nil {
+		t.Fatalf("loadConfig() error = %v", err)
+	}
+	checks := []struct {
+		name      string
+		got, want interface{}
+	}{
+		{"server.port", config.Server.Port, 9090},
+		{"mcp.server_url", config.MCP.ServerURL, "ws://override:9000"},
+		{"mcp.timeout", config.MCP.Timeout, 5 * time.Second},
+		{"mcp.stdio.args", config.MCP.Stdio.Args, []string{"a", "b"}},
+		{"mcp.http.headers", config.MCP.HTTP.Headers, map[string]string{"Authorization": "Bearer x"}},
+		{"mcp.reconnect.replay_tools", config.MCP.Reconnect.ReplayTools, []string{"search"}},
+		{"database.mysql.password", config.Database.MySQL.Password, "secret"},
+		{"log.level", config.Log.Level, "debug"},
+		{"未覆盖的字段保留文件中的值", config.Database.MySQL.Host, "localhost"},
+	}
+	for _, c := range checks {
+		if !reflect.DeepEqual(c.got, c.want) {
+			t.Errorf("%s = %v, want %v", c.name, c.got, c.want)
+		}
+	}
+}
+
+func TestLoadConfigInvalidEnvOverride(t *testing.T) {
+	t.Setenv("MCP_AI_CLIENT_SERVER_PORT", "http")
+	t.Setenv("MCP_AI_CLIENT_MCP_TIMEOUT", "10")
+
+	problems := configProblems(t, baseConfig)
+	want := []string{"MCP_AI_CLIENT_SERVER_PORT (server.port): 应为整数", "MCP_AI_CLIENT_MCP_TIMEOUT (mcp.timeout): 应为时长"}
+	for _, w := range want {
+		if !containsProblem(problems, w) {
+			t.Errorf("problems = %q, want 包含 %q", problems, w)
+		}
+	}
+}
+
+func TestConfigValidation(t *testing.T) {
+	tests := []struct {
+		name  string
+		patch string // 替换 baseConfig 中同名的顶层配置段
+		want  []string
+	}{
+		{name: "有效配置"},
+		{name: "端口为0", patch: "server:\n  port: 0\n", want: []string{"server.port: 必须在 1-65535 之间（当前 0）"}},
+		{name: "端口超出范围", patch: "server:\n  port: 70000\n", want: []string{"server.port: 必须在 1-65535 之间（当前 70000）"}},
+		{name: "未知的传输方式", patch: "mcp:\n  transport: grpc\n  server_url: ws://x\n", want: []string{`mcp.transport: 必须是 websocket、stdio 或 http（当前 "grpc"）`}},
+		{name: "传输方式与地址协议不符", patch: "mcp:\n  transport: http\n  server_url: ws://x\n", want: []string{"mcp.server_url: 协议必须是 http 或 https"}},
+		{name: "stdio缺少命令", patch: "mcp:\n  transport: stdio\n", want: []string{"mcp.stdio.command: 不能为空"}},
+		{name: "无效的重放策略", patch: "mcp:\n  server_url: ws://x\n  reconnect:\n    inflight_policy: retry\n", want: []string{"mcp.reconnect.inflight_policy"}},
+		{name: "无效的日志级别", patch: "log:\n  level: verbose\n", want: []string{"log.level"}},
+		{
+			name:  "汇总全部问题",
+			patch: "server:\n  port: -1\nmcp:\n  transport: grpc\n",
+			want:  []string{"server.port", "mcp.transport"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			problems := configProblems(t, mergeYAML(t, baseConfig, tt.patch))
+			if len(problems) != len(tt.want) {
+				t.Fatalf("problems = %q, want %d 项", problems, len(tt.want))
+			}
+			for _, w := range tt.want {
+				if !containsProblem(problems, w) {
+					t.Errorf("problems = %q, want 包含 %q", problems, w)
+				}
+			}
+		})
+	}
+}
+
+// mergeYAML 将 patch 中的顶层字段替换到 base 中（整段替换）
+func mergeYAML(t *testing.T, base, patch string) string {
+	t.Helper()
+	var b, p map[string]interface{}
+	if err := yaml.Unmarshal([]byte(base), &b); err != nil {
+		t.Fatalf("解析YAML失败: %v", err)
+	}
+	if err := yaml.Unmarshal([]byte(patch), &p); err != nil {
+		t.Fatalf("解析YAML失败: %v", err)
+	}
+	for k, v := range p {
+		b[k] = v
+	}
+	out, err := yaml.Marshal(b)
+	if err != nil {
+		t.Fatalf("生成YAML失败: %v", err)
+	}
+	return string(out)
+}
+
+// containsProblem problems 中是否有包含 want 的一项
+func containsProblem(problems []string, want string) bool {
+	for _, p := range problems {
+		if strings.Contains(p, want) {
+			return true
+		}
+	}
+	return false
+}
@@ -2,148 +2,311 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
-	"log"
+	"log/slog"
 	"mcp-ai-client/internal/api"
 	"mcp-ai-client/internal/database"
+	"mcp-ai-client/internal/logging"
 	"mcp-ai-client/internal/mcp"
+	"mcp-ai-client/internal/metrics"
+	"mcp-ai-client/internal/tracing"
+	"net"
+	"net/http"
 	"os"
+	"os/signal"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"gopkg.in/yaml.v3"
 )
 
-// Config 配置结构
-type Config struct {
-	Server struct {
-		Port int    `yaml:"port"`
-		Host string `yaml:"host"`
-	} `yaml:"server"`
-	Database struct {
-		MySQL  database.MySQLConfig `yaml:"mysql"`
-		Tables struct {
-			UserTable string `yaml:"user_table"`
-		} `yaml:"tables"`
-	} `yaml:"database"`
-	MCP struct {
-		ServerURL string        `yaml:"server_url"`
-		Timeout   time.Duration `yaml:"timeout"`
-		Database  struct {
-			Alias  string `yaml:"alias"`
-			Driver string `yaml:"driver"`
-			DSN    string `yaml:"dsn"`
-		} `yaml:"database"`
-	} `yaml:"mcp"`
-	AI struct {
-		ResponseLanguage           string `yaml:"response_language"`
-		DefaultProvider            string `yaml:"default_provider"`
-		DefaultModel               string `yaml:"default_model"`
-		IncludeLanguageInstruction bool   `yaml:"include_language_instruction"`
-	} `yaml:"ai"`
+// 构建信息，由 Makefile 通过 -ldflags 注入
+var (
+	Version   = "dev"
+	BuildTime = "unknown"
+	GitCommit = "unknown"
+)
+
+// command 子命令
+type command struct {
+	name  string
+	usage string
+	run   func(args []string) error
+}
+
+// commands 可用子命令，未指定时执行 serve
+var commands = []command{
+	{"serve", "启动HTTP服务器（默认）", runServe},
+	{"chat", "AI对话: chat [选项] \"<prompt>\"", runChat},
+	{"file", "AI文件管理: file [选项] \"<instruction>\"", runFileManager},
+	{"data", "AI数据处理: data [选项] \"<instruction>\"", runDataProcessor},
+	{"api", "AI网络请求: api [选项] \"<instruction>\"", runAPIClient},
+	{"db", "AI数据库查询: db [选项] \"<description>\"", runQueryWithAnalysis},
+	{"demo", "依次演示5个AI工具", runDemo},
+	{"repl", "交互式调用MCP工具", runREPL},
+	{"migrate", "数据库迁移: migrate [up|down|status]", runMigrate},
+	{"version", "显示版本信息", runVersion},
+}
+
+func main() {
+	name, args := "serve", os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
+	}
+	if name == "help" {
+		printUsage()
+		return
+	}
+
+	for _, cmd := range commands {
+		if cmd.name != name {
+			continue
+		}
+		if err := cmd.run(args); err != nil {
+			if err != flag.ErrHelp {
+				fmt.Fprintf(os.Stderr, "❌ %s: %v\n", name, err)
+			}
+			os.Exit(exitCode(err))
+		}
+		return
+	}
+
+	fmt.Fprintf(os.Stderr, "未知命令: %s\n\n", name)
+	printUsage()
+	os.Exit(2)
+}
+
+// printUsage 输出命令列表
+func printUsage() {
+	fmt.Fprintln(os.Stderr, "用法: mcp-ai-client <命令> [选项]")
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "命令:")
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-8s %s\n", cmd.name, cmd.usage)
+	}
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "使用 mcp-ai-client <命令> -h 查看命令选项")
 }
 
-// loadConfig 加载配置文件
-func loadConfig(configPath string) (*Config, error) {
-	data, err := os.ReadFile(configPath)
+// runVersion 输出构建信息
+func runVersion(args []string) error {
+	fmt.Printf("mcp-ai-client %s (commit %s, built %s)\n", Version, GitCommit, BuildTime)
+	return nil
+}
+
+// setupLogger 按配置创建 logger 并设为默认，标准库 log 的输出也经由它写出
+// 返回的 LevelVar 用于热加载时调整日志级别
+func setupLogger(config *Config) (*slog.LevelVar, error) {
+	logger, level, err := logging.New(os.Stderr, config.Log)
 	if err != nil {
 		return nil, err
 	}
+	slog.SetDefault(logger)
+	return level, nil
+}
 
-	var config Config
-	if err := yaml.Unmarshal(data, &config); err != nil {
+// newMCPClient 按配置创建MCP客户端（尚未握手），trace 不为 nil 时观察原始报文
+// 日志写入默认 logger（见 setupLogger）
+func newMCPClient(config *Config, trace mcp.TraceFunc) (*mcp.MCPClient, error) {
+	dialer, err := mcp.NewDialer(mcp.TransportConfig{
+		Type:      config.MCP.Transport,
+		ServerURL: config.MCP.ServerURL,
+		Stdio:     config.MCP.Stdio,
+		HTTP:      config.MCP.HTTP,
+		Logger:    slog.Default(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("MCP传输配置错误: %w", err)
+	}
+	if trace != nil {
+		dialer = mcp.TraceDialer(dialer, trace)
+	}
+	return mcp.NewMCPClient(dialer, config.MCP.Timeout, &config.MCP.Reconnect, slog.Default())
+}
+
+// connectMCP 创建MCP客户端并完成握手
+func connectMCP(config *Config, trace mcp.TraceFunc) (*mcp.MCPClient, error) {
+	client, err := newMCPClient(config, trace)
+	if err != nil {
 		return nil, err
 	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := client.Initialize(ctx); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("MCP连接失败: %w", err)
+	}
+	return client, nil
+}
 
-	return &config, nil
+// newAIConfig 从配置创建处理器使用的AI配置
+func newAIConfig(config *Config) *api.AIConfig {
+	return &api.AIConfig{
+		AIToolConfig: config.AI.AIToolConfig,
+		Conversation: config.AI.Conversation,
+	}
 }
 
-func main() {
-	log.Println("🚀 启动MCP AI Client - 简化版")
-	log.Println("📋 功能: 5类AI增强工具 + 基础数据库查询")
+// newDatabaseConfig 从配置创建处理器使用的数据库配置
+// 开启历史记录但历史表的迁移尚未执行时禁用历史记录，避免每次写入都失败
+func newDatabaseConfig(config *Config, mysqlClient *database.MySQLClient, logger *slog.Logger) *api.DatabaseConfig {
+	dbConfig := &api.DatabaseConfig{
+		UserTable:      config.Database.Tables.UserTable,
+		HistoryEnabled: config.Database.History.Enabled,
+	}
+	if dbConfig.HistoryEnabled && mysqlClient != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		ready, err := mysqlClient.HistorySchemaReady(ctx)
+		cancel()
+		switch {
+		case err != nil:
+			logger.Error("无法确认历史记录表是否已创建，历史记录已禁用", "error", err)
+			dbConfig.HistoryEnabled = false
+		case !ready:
+			logger.Error("历史记录表的迁移尚未执行，历史记录已禁用；请运行 mcp-ai-client migrate up 后重启服务",
+				"migration", database.HistoryMigrationVersion)
+			dbConfig.HistoryEnabled = false
+		}
+	}
+	return dbConfig
+}
+
+// runServe 启动HTTP服务器
+func runServe(args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	configPath := fs.String("config", defaultConfigPath, "配置文件路径")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	// 加载配置，失败时由 main 输出错误
+	config, err := loadConfig(*configPath, scopeServer|scopeDatabase|scopeMCP)
+	if err != nil {
+		return fmt.Errorf("加载配置失败: %w", err)
+	}
+	logLevel, err := setupLogger(config)
+	if err != nil {
+		return fmt.Errorf("初始化日志失败: %w", err)
+	}
+	logger := slog.Default()
+	logger.Info("启动MCP AI Client - 简化版", "version", Version, "config", *configPath)
 
-	// 加载配置
-	config, err := loadConfig("configs/config.yaml")
+	// 链路追踪需在创建MySQL和MCP客户端之前启用
+	shutdownTracing, err := tracing.Setup(context.Background(), config.Tracing, Version)
 	if err != nil {
-		log.Fatalf("加载配置失败: %v", err)
+		logger.Error("初始化链路追踪失败", "error", err)
+		os.Exit(1)
+	}
+	if config.Tracing.Enabled() {
+		logger.Info("链路追踪已启用", "exporter", config.Tracing.Exporter, "sample_ratio", config.Tracing.SampleRatio)
 	}
 
 	// 1. 初始化MySQL客户端 (基础数据库服务)
-	log.Println("🔗 初始化MySQL数据库连接...")
-	mysqlClient, err := database.NewMySQLClient(&config.Database.MySQL)
+	logger.Info("初始化MySQL数据库连接")
+	mysqlClient, err := database.NewMySQLClient(&config.Database.MySQL, logger)
 	if err != nil {
-		log.Fatalf("初始化MySQL客户端失败: %v", err)
+		logger.Error("初始化MySQL客户端失败", "error", err)
+		os.Exit(1)
+	}
+	logger.Info("MySQL连接成功")
+
+	// 执行数据库迁移（多实例同时启动时由迁移锁串行执行）
+	migrateCtx, migrateCancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	if config.Database.Migrations.Auto {
+		applied, err := mysqlClient.Migrate(migrateCtx)
+		if err != nil {
+			logger.Error("数据库迁移失败", "error", err)
+			os.Exit(1)
+		}
+		logger.Info("数据库迁移完成", "applied", len(applied))
+	} else if states, err := mysqlClient.MigrationStatus(migrateCtx); err != nil {
+		logger.Warn("查询迁移状态失败", "error", err)
+	} else {
+		pending := 0
+		for _, state := range states {
+			if !state.Applied {
+				pending++
+			}
+		}
+		if pending > 0 {
+			logger.Warn("有数据库迁移未执行，请运行 mcp-ai-client migrate up", "pending", pending)
+		}
+	}
+	migrateCancel()
+	if table := config.Database.Tables.UserTable; table != database.DefaultUserTable {
+		logger.Warn("用户表不由数据库迁移创建，请确认该表已存在", "table", table, "migrated_table", database.DefaultUserTable)
 	}
-	defer mysqlClient.Close()
-	log.Println("✅ MySQL连接成功")
 
 	// 2. 初始化MCP客户端 (AI增强服务)
-	log.Println("🤖 初始化MCP AI客户端...")
-	mcpClient, err := mcp.NewMCPClient(config.MCP.ServerURL, config.MCP.Timeout)
+	logger.Info("初始化MCP AI客户端", "transport", config.MCP.Transport)
+	mcpClient, err := connectMCP(config, nil)
 	if err != nil {
-		log.Fatalf("MCP客户端初始化失败: %v", err)
+		logger.Error("MCP客户端初始化失败", "error", err)
+		os.Exit(1)
 	}
-	defer mcpClient.Close()
+	logger.Info("MCP连接成功")
 
-	// 测试MCP连接
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	if err := mcpClient.Initialize(ctx); err != nil {
-		log.Fatalf("MCP连接失败: %v", err)
+	// 发现服务端提供的工具
+	tools, err := mcpClient.ListTools(ctx)
+	if err != nil {
+		logger.Warn("获取MCP工具列表失败", "error", err)
+	} else {
+		logger.Info("MCP服务端工具已发现", "tools", len(tools))
+		for _, tool := range tools {
+			logger.Debug("MCP工具", "tool", tool.Name, "description", tool.Description)
+		}
 	}
-	log.Println("✅ MCP连接成功")
 
 	// 3. 创建AI配置
-	aiConfig := &api.AIConfig{
-		ResponseLanguage:           config.AI.ResponseLanguage,
-		DefaultProvider:            config.AI.DefaultProvider,
-		DefaultModel:               config.AI.DefaultModel,
-		IncludeLanguageInstruction: config.AI.IncludeLanguageInstruction,
-	}
-
-	// 设置默认值
-	if aiConfig.ResponseLanguage == "" {
-		aiConfig.ResponseLanguage = "zh-CN"
-	}
-	if aiConfig.DefaultProvider == "" {
-		aiConfig.DefaultProvider = "ollama"
-	}
-	if aiConfig.DefaultModel == "" {
-		aiConfig.DefaultModel = "llama2:7b"
-	}
+	aiConfig := newAIConfig(config)
 
-	log.Printf("✅ AI配置: 语言=%s, 提供商=%s, 模型=%s",
-		aiConfig.ResponseLanguage, aiConfig.DefaultProvider, aiConfig.DefaultModel)
+	logger.Info("AI配置",
+		"language", aiConfig.ResponseLanguage, "provider", aiConfig.DefaultProvider, "model", aiConfig.DefaultModel)
 
 	// 4. 创建数据库配置
-	dbConfig := &api.DatabaseConfig{
-		UserTable: config.Database.Tables.UserTable,
-	}
-	if dbConfig.UserTable == "" {
-		dbConfig.UserTable = "mcp_user" // 默认表名
+	dbConfig := newDatabaseConfig(config, mysqlClient, logger)
+
+	if dbConfig.HistoryEnabled {
+		logger.Info("历史记录已启用")
 	}
 
 	// 5. 创建API处理器
-	log.Println("🌐 初始化API处理器...")
-	handlers := api.NewHandlers(mysqlClient, mcpClient, aiConfig, dbConfig)
-	log.Println("✅ API处理器已就绪")
+	handlers := api.NewHandlers(mysqlClient, mcpClient, aiConfig, dbConfig, logger)
+	logger.Info("API处理器已就绪")
+
+	// 服务指标
+	serverMetrics := metrics.New()
+	serverMetrics.InstrumentMCP(mcpClient)
+
+	// 配置热加载：SIGHUP 或配置文件变化时重新加载
+	reloader := newConfigReloader(*configPath, config, handlers, serverMetrics, logLevel, logger, mysqlClient, mcpClient)
+	reloadCtx, stopReload := context.WithCancel(context.Background())
+	defer stopReload()
+	go reloader.run(reloadCtx)
+	serverMetrics.CollectDBStats(reloader.dbStats)
 
 	// 6. 设置HTTP服务器
-	log.Println("🌍 配置HTTP服务器...")
 	gin.SetMode(gin.ReleaseMode)
 	r := gin.Default()
 
 	// 添加中间件
 	r.Use(gin.Logger())
 	r.Use(gin.Recovery())
+	r.Use(serverMetrics.GinMiddleware())
+	r.Use(tracing.GinMiddleware("/health", "/health/live", "/health/ready", "/metrics"))
+	r.Use(api.RequestIDMiddleware())
 
 	// CORS中间件
 	r.Use(func(c *gin.Context) {
 		c.Header("Access-Control-Allow-Origin", "*")
 		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		c.Header("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		c.Header("Access-Control-Allow-Headers", "Content-Type, Authorization, "+api.RequestIDHeader)
+		c.Header("Access-Control-Expose-Headers", api.RequestIDHeader)
 
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(204)
@@ -167,9 +330,10 @@ func main() {
 				"基础数据库查询",
 			},
 			"api_groups": gin.H{
-				"health":    "/health",
-				"ai_tools":  "/api/v1/ai/*",
-				"database":  "/api/v1/db/*",
+				"health":   "/health",
+				"ai_tools": "/api/v1/ai/*",
+				"tools":    "/api/v1/tools",
+				"database": "/api/v1/db/*",
 			},
 			"timestamp": time.Now().Format(time.RFC3339),
 		})
@@ -177,26 +341,60 @@ func main() {
 
 	// 健康检查
 	r.GET("/health", handlers.HealthCheck)
+	r.GET("/health/live", handlers.LivenessHandler)
+	r.GET("/health/ready", handlers.ReadinessHandler)
+
+	// Prometheus 指标
+	r.GET("/metrics", gin.WrapH(serverMetrics.Handler()))
 
 	// ===== AI工具API路由 (5.1-5.5) =====
 	aiV1 := r.Group("/api/v1/ai")
 	{
 		// 5.1 基础AI对话
 		aiV1.POST("/chat", handlers.MCPChatHandler)
-		
+		aiV1.POST("/chat/stream", handlers.MCPChatStreamHandler)
+
 		// 5.2 AI智能文件管理
 		aiV1.POST("/file-manager", handlers.MCPFileManagerHandler)
-		
+
 		// 5.3 AI智能数据处理
 		aiV1.POST("/data-processor", handlers.MCPDataProcessorHandler)
-		
+
 		// 5.4 AI智能网络请求
 		aiV1.POST("/api-client", handlers.MCPAPIClientHandler)
-		
+
 		// 5.5 AI智能数据库查询
 		aiV1.POST("/query-with-analysis", handlers.MCPQueryWithAnalysisHandler)
 	}
 
+	// ===== 通用MCP工具API =====
+	toolsV1 := r.Group("/api/v1/tools")
+	{
+		// 服务端工具目录
+		toolsV1.GET("", handlers.ListToolsHandler)
+
+		// 按名称调用任意工具
+		toolsV1.POST("/:name", handlers.CallToolHandler)
+	}
+
+	// ===== 多轮对话API =====
+	conversationsV1 := r.Group("/api/v1/conversations")
+	{
+		conversationsV1.POST("", handlers.CreateConversationHandler)
+		conversationsV1.GET("/:id", handlers.GetConversationHandler)
+		conversationsV1.DELETE("/:id", handlers.DeleteConversationHandler)
+		conversationsV1.POST("/:id/messages", handlers.SendConversationMessageHandler)
+	}
+
+	// ===== 历史记录查询API =====
+	historyV1 := r.Group("/api/v1/history")
+	{
+		historyV1.GET("/tool-calls", handlers.ListToolCallsHandler)
+		historyV1.GET("/tool-calls/:id", handlers.GetToolCallHandler)
+		historyV1.GET("/conversations", handlers.ListConversationRecordsHandler)
+		historyV1.GET("/messages", handlers.ListMessageRecordsHandler)
+	}
+
 	// ===== 基础数据库查询API =====
 	dbV1 := r.Group("/api/v1/db")
 	{
@@ -204,37 +402,48 @@ func main() {
 		dbV1.GET("/users", handlers.GetUsersTraditional)
 	}
 
-	log.Println("✅ 所有API路由已配置")
+	logger.Info("所有API路由已配置", "routes", len(r.Routes()))
 
 	// 7. 启动服务器
 	addr := fmt.Sprintf("%s:%d", config.Server.Host, config.Server.Port)
 
-	log.Println("🎉 MCP AI Client 简化版启动完成!")
-	log.Println(strings.Repeat("=", 60))
-	log.Printf("📍 服务地址: http://%s", addr)
-	log.Printf("🔍 健康检查: http://%s/health", addr)
-	log.Printf("📖 服务概览: http://%s/", addr)
-	log.Println()
-
-	log.Println("📋 可用API端点:")
-	log.Println("┌─ AI增强工具 (5.1-5.5)")
-	log.Printf("│  ├─ 5.1 AI对话: POST %s/api/v1/ai/chat", addr)
-	log.Printf("│  ├─ 5.2 文件管理: POST %s/api/v1/ai/file-manager", addr)
-	log.Printf("│  ├─ 5.3 数据处理: POST %s/api/v1/ai/data-processor", addr)
-	log.Printf("│  ├─ 5.4 网络请求: POST %s/api/v1/ai/api-client", addr)
-	log.Printf("│  └─ 5.5 数据库查询: POST %s/api/v1/ai/query-with-analysis", addr)
-	log.Println("│")
-	log.Println("└─ 基础数据库查询")
-	log.Printf("   └─ 用户列表: GET %s/api/v1/db/users", addr)
-	log.Println()
-
-	log.Println("💡 使用说明:")
-	log.Println("  • AI工具: 使用POST请求调用AI增强功能")
-	log.Println("  • 数据库: 使用GET请求进行基础数据查询")
-	log.Println("  • 所有AI工具都支持自然语言交互")
-	log.Println(strings.Repeat("=", 60))
-
-	if err := r.Run(addr); err != nil {
-		log.Fatalf("❌ 启动服务器失败: %v", err)
+	logger.Info("MCP AI Client 简化版启动完成",
+		"addr", "http://"+addr,
+		"health", "/health",
+		"liveness", "/health/live",
+		"readiness", "/health/ready",
+		"metrics", "/metrics")
+	for _, route := range r.Routes() {
+		logger.Debug("API端点", "method", route.Method, "path", route.Path)
+	}
+
+	// 请求上下文派生自 requestCtx，关闭超时后统一取消
+	requestCtx, cancelRequests := context.WithCancel(context.Background())
+	defer cancelRequests()
+	srv := &http.Server{
+		Addr:        addr,
+		Handler:     r,
+		BaseContext: func(net.Listener) context.Context { return requestCtx },
 	}
+
+	signalCtx, stopSignals := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stopSignals()
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serveErr:
+		logger.Error("启动服务器失败", "addr", addr, "error", err)
+		os.Exit(1)
+	case <-signalCtx.Done():
+	}
+	// 恢复默认信号处理：关闭过程中再次收到信号时立即退出
+	stopSignals()
+	stopReload()
+
+	gracefulShutdown(srv, cancelRequests, handlers, reloader, shutdownTracing, reloader.current().Server.ShutdownTimeout, logger)
+	return nil
 }
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"mcp-ai-client/internal/database"
+	"strings"
+	"time"
+)
+
+// runMigrate 执行 migrate 子命令
+//
+//	mcp-ai-client migrate [-config path] [up|down|status] [-steps N]
+func runMigrate(args []string) error {
+	fs := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	configPath := fs.String("config", defaultConfigPath, "配置文件路径")
+	steps := fs.Int("steps", 1, "down 回滚的版本数")
+	timeout := fs.Duration("timeout", 5*time.Minute, "迁移超时时间")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "用法: mcp-ai-client migrate [选项] [up|down|status]")
+		fmt.Fprintln(fs.Output(), "  up      执行全部未执行的迁移（默认）")
+		fmt.Fprintln(fs.Output(), "  down    回滚最近的 -steps 个迁移")
+		fmt.Fprintln(fs.Output(), "  status  查看迁移执行状态")
+		fs.PrintDefaults()
+	}
+	// 允许选项写在动作之后，如 migrate down -steps 2
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+
+	action := "up"
+	if len(positional) > 0 {
+		action = positional[0]
+	}
+	if len(positional) > 1 || (action != "up" && action != "down" && action != "status") {
+		fs.Usage()
+		return fmt.Errorf("%w: 未知的迁移动作: %s", errUsage, strings.Join(positional, " "))
+	}
+
+	config, err := loadConfig(*configPath, scopeDatabase)
+	if err != nil {
+		return fmt.Errorf("加载配置失败: %v", err)
+	}
+	if _, err := setupLogger(config); err != nil {
+		return err
+	}
+	mysqlClient, err := database.NewMySQLClient(&config.Database.MySQL, slog.Default())
+	if err != nil {
+		return err
+	}
+	defer mysqlClient.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	switch action {
+	case "up":
+		applied, err := mysqlClient.Migrate(ctx)
+		if err != nil {
+			return err
+		}
+		if len(applied) == 0 {
+			fmt.Println("数据库已是最新版本")
+		}
+		for _, m := range applied {
+			fmt.Printf("已执行 %04d_%s\n", m.Version, m.Name)
+		}
+	case "down":
+		reverted, err := mysqlClient.MigrateDown(ctx, *steps)
+		if err != nil {
+			return err
+		}
+		if len(reverted) == 0 {
+			fmt.Println("没有可回滚的迁移")
+		}
+		for _, m := range reverted {
+			fmt.Printf("已回滚 %04d_%s\n", m.Version, m.Name)
+		}
+	case "status":
+		states, err := mysqlClient.MigrationStatus(ctx)
+		if err != nil {
+			return err
+		}
+		printMigrationStatus(states)
+	}
+	return nil
+}
+
+// printMigrationStatus 以表格输出迁移状态
+func printMigrationStatus(states []database.MigrationState) {
+	fmt.Printf("%-8s %-32s %-10s %s\n", "VERSION", "NAME", "STATUS", "APPLIED AT")
+	for _, state := range states {
+		status, appliedAt := "pending", "-"
+		if state.Applied {
+			status = "applied"
+			appliedAt = state.AppliedAt.Format(time.RFC3339)
+		}
+		if state.Missing {
+			status = "missing"
+		}
+		fmt.Printf("%04d     %-32s %-10s %s\n", state.Version, state.Name, status, appliedAt)
+	}
+}
@@ -0,0 +1,380 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"mcp-ai-client/internal/api"
+	"mcp-ai-client/internal/database"
+	"mcp-ai-client/internal/logging"
+	"mcp-ai-client/internal/mcp"
+	"mcp-ai-client/internal/metrics"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// reloadDebounce 配置文件变化后等待的时间，编辑器保存时通常会连续产生多个事件
+const reloadDebounce = 500 * time.Millisecond
+
+// retireDelay 被替换的旧连接延迟关闭的时间，不短于最长的处理器超时，让在途请求完成
+const retireDelay = 2 * time.Minute
+
+// sensitiveConfigKeys 差异日志中不输出值的配置项
+var sensitiveConfigKeys = map[string]bool{
+	"password": true,
+	"dsn":      true,
+	"headers":  true,
+	"env":      true,
+}
+
+// restartOnlyConfig 需要重启才能生效的配置项
+var restartOnlyConfig = []string{"server.host", "server.port", "server.watch_config", "log.format", "log.max_payload", "tracing"}
+
+// mcpConnectionConfig 变化时需要重新连接MCP的配置项（mcp.database 只是传给工具的参数）
+var mcpConnectionConfig = []string{"mcp.transport", "mcp.server_url", "mcp.stdio.", "mcp.http.", "mcp.timeout", "mcp.reconnect."}
+
+// configChange 一项配置变化
+type configChange struct {
+	path     string
+	old, new string
+}
+
+// configReloader 配置热加载：收到 SIGHUP 或配置文件变化时重新加载配置并原子替换处理器的配置，
+// 只有MySQL或MCP的连接参数变化时才建立新连接，旧连接延迟关闭；新配置无效或连接失败时保留当前配置
+// 建立新连接和迁移期间不持有 mu，指标采集和关闭流程不会被阻塞
+type configReloader struct {
+	path     string
+	handlers *api.Handlers
+	metrics  *metrics.Metrics
+	logLevel *slog.LevelVar
+	logger   *slog.Logger
+
+	mu          sync.Mutex
+	config      *Config
+	digest      [sha256.Size]byte
+	mysqlClient *database.MySQLClient
+	mcpClient   *mcp.MCPClient
+	retiring    []*retiredConn
+	closed      bool
+}
+
+// retiredConn 等待关闭的旧连接
+type retiredConn struct {
+	name     string
+	closeFn  func() error
+	cancelFn func(reason string) int // 放弃在途请求，MySQL连接为 nil
+	timer    *time.Timer
+	logger   *slog.Logger
+}
+
+// close 关闭连接
+func (c *retiredConn) close() {
+	if err := c.closeFn(); err != nil {
+		c.logger.Warn("关闭旧连接失败", "conn", c.name, "error", err)
+	}
+}
+
+// newConfigReloader 创建配置热加载器，config 和连接为当前正在使用的
+func newConfigReloader(path string, config *Config, handlers *api.Handlers, m *metrics.Metrics, logLevel *slog.LevelVar, logger *slog.Logger, mysqlClient *database.MySQLClient, mcpClient *mcp.MCPClient) *configReloader {
+	r := &configReloader{
+		path:        path,
+		handlers:    handlers,
+		metrics:     m,
+		logLevel:    logLevel,
+		logger:      logger,
+		config:      config,
+		mysqlClient: mysqlClient,
+		mcpClient:   mcpClient,
+	}
+	if data, err := os.ReadFile(path); err == nil {
+		r.digest = sha256.Sum256(data)
+	}
+	return r
+}
+
+// run 监听 SIGHUP 和配置文件变化（server.watch_config 为 true 时），直到 ctx 结束
+// 重新加载只在本协程中执行，不会并发
+func (r *configReloader) run(ctx context.Context) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	var events <-chan fsnotify.Event
+	var errs <-chan error
+	if r.current().Server.WatchConfig {
+		watcher, err := fsnotify.NewWatcher()
+		if err != nil {
+			r.logger.Warn("无法监听配置文件，仅支持 SIGHUP 重新加载", "error", err)
+		} else {
+			defer watcher.Close()
+			// 监听所在目录：编辑器保存和 Kubernetes ConfigMap 更新通常是替换文件而不是原地写入
+			if err := watcher.Add(filepath.Dir(r.path)); err != nil {
+				r.logger.Warn("无法监听配置文件，仅支持 SIGHUP 重新加载", "path", r.path, "error", err)
+			} else {
+				events, errs = watcher.Events, watcher.Errors
+				r.logger.Info("监听配置文件变化", "path", r.path)
+			}
+		}
+	}
+
+	debounce := time.NewTimer(time.Hour)
+	debounce.Stop()
+	defer debounce.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			r.logger.Info("收到 SIGHUP，重新加载配置")
+			r.reload(true)
+		case <-events:
+			// 目录内任何变化都检查一次，内容未变时不重新加载
+			debounce.Reset(reloadDebounce)
+		case <-debounce.C:
+			r.reload(false)
+		case err := <-errs:
+			r.logger.Warn("监听配置文件出错", "path", r.path, "error", err)
+		}
+	}
+}
+
+// current 返回当前生效的配置
+func (r *configReloader) current() *Config {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.config
+}
+
+// reload 重新加载配置；force 为 false 时文件内容未变化则跳过
+// 新连接在不持有 mu 的情况下建立，最后持有 mu 替换状态
+func (r *configReloader) reload(force bool) {
+	r.mu.Lock()
+	current, currentDigest := r.config, r.digest
+	oldMySQL, oldMCP := r.mysqlClient, r.mcpClient
+	r.mu.Unlock()
+
+	data, err := os.ReadFile(r.path)
+	if err != nil {
+		r.logger.Error("读取配置文件失败，继续使用当前配置", "path", r.path, "error", err)
+		return
+	}
+	digest := sha256.Sum256(data)
+	if !force && digest == currentDigest {
+		return
+	}
+
+	config, err := loadConfig(r.path, scopeServer|scopeDatabase|scopeMCP)
+	if err != nil {
+		r.logger.Error("重新加载配置失败，继续使用当前配置", "path", r.path, "error", err)
+		return
+	}
+
+	changes := diffConfig(current, config)
+	if len(changes) == 0 {
+		r.mu.Lock()
+		r.digest = digest
+		r.mu.Unlock()
+		r.logger.Info("配置未变化", "path", r.path)
+		return
+	}
+	r.logger.Info("配置已变化", "path", r.path, "changes", len(changes))
+	for _, change := range changes {
+		r.logger.Info("配置项变化", "key", change.path, "old", change.old, "new", change.new)
+	}
+	for _, path := range restartOnlyConfig {
+		if changed(changes, path) {
+			r.logger.Warn("配置项需要重启服务才能生效", "key", path)
+		}
+	}
+
+	mysqlClient, mcpClient := oldMySQL, oldMCP
+	// discard 关闭本次新建但未启用的连接
+	discard := func() {
+		if mysqlClient != oldMySQL {
+			mysqlClient.Close()
+		}
+		if mcpClient != oldMCP {
+			mcpClient.Close()
+		}
+	}
+	if changed(changes, "database.mysql.") {
+		r.logger.Info("MySQL配置已变化，重新连接")
+		mysqlClient, err = database.NewMySQLClient(&config.Database.MySQL, r.logger)
+		if err != nil {
+			r.logger.Error("重新连接MySQL失败，继续使用当前配置", "error", err)
+			return
+		}
+		if config.Database.Migrations.Auto {
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+			_, err = mysqlClient.Migrate(ctx)
+			cancel()
+			if err != nil {
+				discard()
+				r.logger.Error("数据库迁移失败，继续使用当前配置", "error", err)
+				return
+			}
+		}
+	}
+	if changed(changes, mcpConnectionConfig...) {
+		r.logger.Info("MCP配置已变化，重新连接")
+		mcpClient, err = connectMCP(config, nil)
+		if err != nil {
+			mcpClient = oldMCP
+			discard()
+			r.logger.Error("重新连接MCP失败，继续使用当前配置", "error", err)
+			return
+		}
+		r.metrics.InstrumentMCP(mcpClient)
+	}
+
+	dbConfig := newDatabaseConfig(config, mysqlClient, r.logger)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		// 重新连接期间服务已开始关闭
+		discard()
+		return
+	}
+	r.handlers.Update(mysqlClient, mcpClient, newAIConfig(config), dbConfig)
+	if changed(changes, "log.level") {
+		level, _ := logging.ParseLevel(config.Log.Level) // 已通过校验
+		r.logLevel.Set(level)
+	}
+
+	if mysqlClient != oldMySQL {
+		r.retire("MySQL", oldMySQL.Close, nil)
+	}
+	if mcpClient != oldMCP {
+		r.retire("MCP", oldMCP.Close, oldMCP.CancelPending)
+	}
+	r.config, r.digest = config, digest
+	r.mysqlClient, r.mcpClient = mysqlClient, mcpClient
+	r.logger.Info("配置已重新加载", "path", r.path)
+}
+
+// retire 延迟关闭被替换的连接，调用方持有 r.mu
+func (r *configReloader) retire(name string, closeFn func() error, cancelFn func(string) int) {
+	conn := &retiredConn{name: name, closeFn: closeFn, cancelFn: cancelFn, logger: r.logger}
+	conn.timer = time.AfterFunc(retireDelay, func() { r.closeRetired(conn) })
+	r.retiring = append(r.retiring, conn)
+}
+
+// closeRetired 到期后关闭旧连接并从等待列表中移除
+func (r *configReloader) closeRetired(conn *retiredConn) {
+	r.mu.Lock()
+	for i, c := range r.retiring {
+		if c == conn {
+			r.retiring = append(r.retiring[:i], r.retiring[i+1:]...)
+			break
+		}
+	}
+	r.mu.Unlock()
+	conn.close()
+}
+
+// dbStats 返回当前MySQL连接池统计
+func (r *configReloader) dbStats() (sql.DBStats, bool) {
+	r.mu.Lock()
+	mysqlClient := r.mysqlClient
+	r.mu.Unlock()
+	if mysqlClient == nil {
+		return sql.DBStats{}, false
+	}
+	return mysqlClient.Stats(), true
+}
+
+// cancelPending 放弃当前和待关闭的MCP连接上的在途请求并通知服务端取消，返回放弃的请求数
+func (r *configReloader) cancelPending(reason string) int {
+	r.mu.Lock()
+	cancelFns := []func(string) int{r.mcpClient.CancelPending}
+	for _, conn := range r.retiring {
+		if conn.cancelFn != nil {
+			cancelFns = append(cancelFns, conn.cancelFn)
+		}
+	}
+	r.mu.Unlock()
+
+	n := 0
+	for _, cancel := range cancelFns {
+		n += cancel(reason)
+	}
+	return n
+}
+
+// close 关闭当前连接和等待关闭的旧连接，之后完成的重新加载不再启用新连接
+func (r *configReloader) close() {
+	r.mu.Lock()
+	r.closed = true
+	var closing []*retiredConn
+	for _, conn := range r.retiring {
+		// 已触发的定时器由其自身完成关闭
+		if conn.timer.Stop() {
+			closing = append(closing, conn)
+		}
+	}
+	r.retiring = nil
+	mysqlClient, mcpClient := r.mysqlClient, r.mcpClient
+	r.mu.Unlock()
+
+	for _, conn := range closing {
+		conn.close()
+	}
+	mcpClient.Close()
+	if mysqlClient != nil {
+		mysqlClient.Close()
+	}
+}
+
+// diffConfig 按 yaml 路径比较两份配置，敏感项不输出值
+func diffConfig(old, new *Config) []configChange {
+	oldValues := make(map[string]reflect.Value)
+	walkConfig(reflect.ValueOf(old).Elem(), "", func(path string, field reflect.Value) {
+		oldValues[path] = field
+	})
+
+	var changes []configChange
+	walkConfig(reflect.ValueOf(new).Elem(), "", func(path string, field reflect.Value) {
+		before := oldValues[path]
+		if reflect.DeepEqual(before.Interface(), field.Interface()) {
+			return
+		}
+		change := configChange{path: path, old: "(已修改)", new: "(已修改)"}
+		if !sensitiveConfigKeys[path[strings.LastIndex(path, ".")+1:]] {
+			change.old, change.new = formatConfigValue(before), formatConfigValue(field)
+		}
+		changes = append(changes, change)
+	})
+	return changes
+}
+
+// formatConfigValue 格式化配置值，字符串带引号以区分空值
+func formatConfigValue(v reflect.Value) string {
+	if v.Kind() == reflect.String {
+		return fmt.Sprintf("%q", v.String())
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+// changed 是否有等于或以任一 prefix 开头的配置项变化
+func changed(changes []configChange, prefixes ...string) bool {
+	for _, change := range changes {
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(change.path, prefix) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,508 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"mcp-ai-client/internal/mcp"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/peterh/liner"
+)
+
+// replCommands REPL内置命令
+var replCommands = []struct{ name, usage string }{
+	{"tools", "tools [refresh]            列出服务端工具"},
+	{"describe", "describe <tool>            查看工具参数"},
+	{"call", "call <tool> key:value ...   调用工具，也可省略 call 直接输入工具名"},
+	{"raw", "raw [on|off]               显示/隐藏原始JSON-RPC报文"},
+	{"help", "help                       显示帮助"},
+	{"exit", "exit                       退出（也可按 Ctrl-D）"},
+}
+
+// replSession 交互式会话
+type replSession struct {
+	client  *mcp.MCPClient
+	timeout time.Duration
+	raw     atomic.Bool
+	traceMu sync.Mutex
+}
+
+// runREPL 交互式调用MCP工具
+func runREPL(args []string) error {
+	fs := flag.NewFlagSet("repl", flag.ContinueOnError)
+	configPath := fs.String("config", defaultConfigPath, "配置文件路径")
+	historyPath := fs.String("history", defaultHistoryPath(), "命令历史文件，为空时不保存")
+	timeout := fs.Duration("timeout", 0, "单次调用超时时间，默认使用配置中的 mcp.timeout")
+	raw := fs.Bool("raw", false, "启动时即显示原始JSON-RPC报文")
+	verbose := fs.Bool("v", false, "输出运行日志")
+	if _, err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	if !*verbose {
+		log.SetOutput(io.Discard)
+	}
+
+	config, err := loadConfig(*configPath, scopeMCP)
+	if err != nil {
+		return fmt.Errorf("加载配置失败: %v", err)
+	}
+	if *verbose {
+		if _, err := setupLogger(config); err != nil {
+			return err
+		}
+	}
+
+	session := &replSession{timeout: *timeout}
+	session.raw.Store(*raw)
+	session.client, err = newMCPClient(config, session.trace)
+	if err != nil {
+		return err
+	}
+	defer session.client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := session.client.Initialize(ctx); err != nil {
+		return fmt.Errorf("MCP连接失败: %w", err)
+	}
+	tools, err := session.client.ListTools(ctx)
+	if err != nil {
+		return fmt.Errorf("获取工具列表失败: %w", err)
+	}
+
+	line := liner.NewLiner()
+	defer line.Close()
+	line.SetCtrlCAborts(true)
+	line.SetTabCompletionStyle(liner.TabPrints)
+	line.SetWordCompleter(session.complete)
+
+	if *historyPath != "" {
+		if f, err := os.Open(*historyPath); err == nil {
+			line.ReadHistory(f)
+			f.Close()
+		}
+		defer func() {
+			f, err := os.Create(*historyPath)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "⚠️ 保存命令历史失败: %v\n", err)
+				return
+			}
+			line.WriteHistory(f)
+			f.Close()
+		}()
+	}
+
+	fmt.Printf("已连接 %s，共 %d 个工具。输入 help 查看命令，Tab 补全工具名和参数。\n", config.MCP.ServerURL, len(tools))
+	for {
+		input, err := line.Prompt("mcp> ")
+		if err == liner.ErrPromptAborted {
+			continue
+		}
+		if err != nil {
+			// Ctrl-D 或输入结束
+			fmt.Println()
+			return nil
+		}
+		input = strings.TrimSpace(input)
+		if input == "" {
+			continue
+		}
+		line.AppendHistory(input)
+
+		if session.execute(input) {
+			return nil
+		}
+	}
+}
+
+// defaultHistoryPath 默认命令历史文件
+func defaultHistoryPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".mcp_ai_client_history")
+}
+
+// trace 在开启 raw 时输出原始报文
+func (s *replSession) trace(direction string, data []byte) {
+	if !s.raw.Load() {
+		return
+	}
+	arrow := "→"
+	if direction == mcp.TraceReceive {
+		arrow = "←"
+	}
+	s.traceMu.Lock()
+	fmt.Fprintf(os.Stderr, "%s %s\n", arrow, strings.TrimSpace(string(data)))
+	s.traceMu.Unlock()
+}
+
+// execute 执行一行输入，返回 true 表示退出
+func (s *replSession) execute(input string) bool {
+	tokens, err := tokenize(input)
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return false
+	}
+
+	name, rest := tokens[0].text, tokens[1:]
+	switch name {
+	case "exit", "quit":
+		return true
+	case "help", "?":
+		for _, cmd := range replCommands {
+			fmt.Println("  " + cmd.usage)
+		}
+		fmt.Println("参数示例: call ai_chat prompt:\"你好\" max_tokens:50 temperature:0.7")
+		fmt.Println("  带引号的值按字符串处理；数组可写为 tags:a,b 或 tags:'[\"a\",\"b\"]'；嵌套字段写为 opt.x:1")
+	case "tools":
+		s.listTools(len(rest) > 0 && rest[0].text == "refresh")
+	case "describe":
+		if len(rest) != 1 {
+			fmt.Println("用法: describe <tool>")
+			return false
+		}
+		s.describe(rest[0].text)
+	case "raw":
+		switch {
+		case len(rest) == 0:
+			s.raw.Store(!s.raw.Load())
+		case rest[0].text == "on":
+			s.raw.Store(true)
+		case rest[0].text == "off":
+			s.raw.Store(false)
+		default:
+			fmt.Println("用法: raw [on|off]")
+			return false
+		}
+		if s.raw.Load() {
+			fmt.Println("原始报文: 开启")
+		} else {
+			fmt.Println("原始报文: 关闭")
+		}
+	case "call":
+		if len(rest) == 0 {
+			fmt.Println("用法: call <tool> key:value ...")
+			return false
+		}
+		s.call(rest[0].text, rest[1:])
+	default:
+		if _, ok := s.tool(name); ok {
+			s.call(name, rest)
+			return false
+		}
+		fmt.Printf("未知命令或工具: %s，输入 help 查看命令\n", name)
+	}
+	return false
+}
+
+// tool 从缓存的工具目录查找工具
+func (s *replSession) tool(name string) (mcp.Tool, bool) {
+	for _, tool := range s.client.CachedTools() {
+		if tool.Name == name {
+			return tool, true
+		}
+	}
+	return mcp.Tool{}, false
+}
+
+// listTools 列出工具，refresh 时重新拉取 tools/list
+func (s *replSession) listTools(refresh bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var tools []mcp.Tool
+	var err error
+	if refresh {
+		tools, err = s.client.RefreshTools(ctx)
+	} else {
+		tools, err = s.client.ListTools(ctx)
+	}
+	if err != nil {
+		fmt.Printf("❌ 获取工具列表失败: %v\n", err)
+		return
+	}
+
+	width := 0
+	for _, tool := range tools {
+		if len(tool.Name) > width {
+			width = len(tool.Name)
+		}
+	}
+	for _, tool := range tools {
+		fmt.Printf("  %-*s  %s\n", width, tool.Name, tool.Description)
+	}
+}
+
+// describe 输出工具说明和参数
+func (s *replSession) describe(name string) {
+	tool, ok := s.tool(name)
+	if !ok {
+		fmt.Printf("未知工具: %s\n", name)
+		return
+	}
+
+	fmt.Printf("%s: %s\n", tool.Name, tool.Description)
+	params := schemaParams(tool.InputSchema)
+	if len(params) == 0 {
+		fmt.Println("  (无参数)")
+		return
+	}
+	for _, param := range params {
+		attrs := []string{strings.Join(schemaTypeList(param.schema), "|")}
+		if attrs[0] == "" {
+			attrs[0] = "any"
+		}
+		if param.required {
+			attrs = append(attrs, "必填")
+		}
+		if def, ok := param.schema["default"]; ok {
+			attrs = append(attrs, fmt.Sprintf("默认 %v", def))
+		}
+		desc, _ := param.schema["description"].(string)
+		if values := valueCandidates(param.schema); len(values) > 0 && param.schema["enum"] != nil {
+			desc = strings.TrimSpace(desc + " 可选: " + strings.Join(values, ", "))
+		}
+		fmt.Println(strings.TrimRight(fmt.Sprintf("  %s (%s) %s", param.path, strings.Join(attrs, ", "), desc), " "))
+	}
+}
+
+// call 解析参数并调用工具，等待期间输出进度，Ctrl-C 取消调用
+func (s *replSession) call(name string, tokens []replToken) {
+	tool, ok := s.tool(name)
+	if !ok {
+		fmt.Printf("未知工具: %s\n", name)
+		return
+	}
+	args, err := parseToolArgs(tool.InputSchema, tokens)
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if s.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.timeout)
+		defer cancel()
+	}
+
+	type outcome struct {
+		result *mcp.ToolCallResult
+		err    error
+	}
+	progress := make(chan mcp.Progress, progressBuffer)
+	done := make(chan outcome, 1)
+	start := time.Now()
+
+	go func() {
+		result, err := s.client.CallTool(ctx, name, args, mcp.WithProgress(func(p mcp.Progress) {
+			// 进度回调运行在MCP读取协程中，不能阻塞
+			select {
+			case progress <- p:
+			default:
+			}
+		}))
+		done <- outcome{result: result, err: err}
+	}()
+
+	var o outcome
+	for waiting := true; waiting; {
+		select {
+		case p := <-progress:
+			printProgress(p)
+		case o = <-done:
+			waiting = false
+		}
+	}
+
+	var toolErr *mcp.ToolError
+	switch {
+	case errors.As(o.err, &toolErr):
+		fmt.Println("❌ 工具执行失败:")
+		printToolResult(toolErr.Result)
+	case o.err != nil:
+		fmt.Printf("❌ [%s] %v\n", mcp.ClassifyError(o.err), o.err)
+		return
+	default:
+		printToolResult(o.result)
+	}
+	fmt.Printf("(%s)\n", time.Since(start))
+}
+
+// progressBuffer 未及时输出的进度缓冲，溢出时丢弃新进度
+const progressBuffer = 32
+
+// printProgress 输出一条进度
+func printProgress(p mcp.Progress) {
+	text := fmt.Sprintf("%g", p.Progress)
+	if p.Total > 0 {
+		text = fmt.Sprintf("%g/%g", p.Progress, p.Total)
+	}
+	if p.Message != "" {
+		text += " " + p.Message
+	}
+	fmt.Printf("⏳ %s\n", text)
+}
+
+// printToolResult 按内容块类型输出结果
+func printToolResult(result *mcp.ToolCallResult) {
+	if result == nil {
+		return
+	}
+	for _, block := range result.Content {
+		switch block.Type {
+		case mcp.ContentText:
+			fmt.Println(prettyText(block.Text))
+		case mcp.ContentImage, mcp.ContentAudio:
+			size := len(block.Data)
+			if data, err := base64.StdEncoding.DecodeString(block.Data); err == nil {
+				size = len(data)
+			}
+			fmt.Printf("🖼️ [%s %s, %d 字节]\n", block.Type, block.MimeType, size)
+		case mcp.ContentResourceLink:
+			fmt.Printf("🔗 %s <%s>", block.Name, block.URI)
+			if block.Description != "" {
+				fmt.Printf(" %s", block.Description)
+			}
+			fmt.Println()
+		case mcp.ContentResource:
+			if block.Resource == nil {
+				continue
+			}
+			fmt.Printf("📄 %s", block.Resource.URI)
+			if block.Resource.MimeType != "" {
+				fmt.Printf(" (%s)", block.Resource.MimeType)
+			}
+			fmt.Println()
+			if block.Resource.Text != "" {
+				fmt.Println(prettyText(block.Resource.Text))
+			} else if block.Resource.Blob != "" {
+				fmt.Printf("[二进制内容 %d 字节(base64)]\n", len(block.Resource.Blob))
+			}
+		default:
+			data, _ := json.Marshal(block)
+			fmt.Printf("[%s] %s\n", block.Type, data)
+		}
+	}
+	if result.StructuredContent != nil {
+		data, _ := json.MarshalIndent(result.StructuredContent, "", "  ")
+		fmt.Printf("── structuredContent ──\n%s\n", data)
+	}
+}
+
+// prettyText JSON文本缩进输出（保留原始数字精度），其他文本原样返回
+func prettyText(text string) string {
+	trimmed := strings.TrimSpace(text)
+	if !strings.HasPrefix(trimmed, "{") && !strings.HasPrefix(trimmed, "[") {
+		return text
+	}
+	var out bytes.Buffer
+	if err := json.Indent(&out, []byte(trimmed), "", "  "); err != nil {
+		return text
+	}
+	return out.String()
+}
+
+// complete Tab补全：命令和工具名、工具参数名，以及 enum/布尔参数的取值
+func (s *replSession) complete(line string, pos int) (head string, completions []string, tail string) {
+	head, tail = line[:pos], line[pos:]
+	start := strings.LastIndexAny(head, " \t") + 1
+	word := head[start:]
+	head = head[:start]
+	fields := strings.Fields(head)
+
+	var candidates []string
+	toolNames := func() []string {
+		var names []string
+		for _, tool := range s.client.CachedTools() {
+			names = append(names, tool.Name)
+		}
+		return names
+	}
+
+	switch {
+	case len(fields) == 0:
+		for _, cmd := range replCommands {
+			candidates = append(candidates, cmd.name)
+		}
+		candidates = append(candidates, toolNames()...)
+	case len(fields) == 1 && (fields[0] == "call" || fields[0] == "describe"):
+		candidates = toolNames()
+	case len(fields) == 1 && fields[0] == "raw":
+		candidates = []string{"on", "off"}
+	case len(fields) == 1 && fields[0] == "tools":
+		candidates = []string{"refresh"}
+	default:
+		name := fields[0]
+		if name == "call" {
+			name = fields[1]
+		}
+		tool, ok := s.tool(name)
+		if !ok {
+			return head, nil, tail
+		}
+		candidates = argCandidates(tool, word, fields)
+	}
+
+	for _, candidate := range candidates {
+		if strings.HasPrefix(candidate, word) {
+			completions = append(completions, candidate)
+		}
+	}
+	sort.Strings(completions)
+	// 唯一的命令、工具名或完整参数值补全后追加空格
+	if len(completions) == 1 && !strings.HasSuffix(completions[0], ":") {
+		completions[0] += " "
+	}
+	return head, completions, tail
+}
+
+// argCandidates 工具参数的补全候选：未填写的参数名（key:）或当前参数的可选值（key:value）
+func argCandidates(tool mcp.Tool, word string, fields []string) []string {
+	params := schemaParams(tool.InputSchema)
+
+	if key, _, ok := strings.Cut(word, ":"); ok {
+		for _, param := range params {
+			if param.path != key {
+				continue
+			}
+			var candidates []string
+			for _, value := range valueCandidates(param.schema) {
+				candidates = append(candidates, key+":"+value)
+			}
+			return candidates
+		}
+		return nil
+	}
+
+	used := make(map[string]bool)
+	for _, field := range fields {
+		if key, _, ok := strings.Cut(field, ":"); ok {
+			used[key] = true
+		}
+	}
+	var candidates []string
+	for _, param := range params {
+		if !used[param.path] {
+			candidates = append(candidates, param.path+":")
+		}
+	}
+	return candidates
+}
@@ -0,0 +1,295 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// replToken 命令行中的一个参数，quoted 表示值部分带引号（按字符串处理）
+type replToken struct {
+	text   string
+	quoted bool
+}
+
+// tokenize 按空白拆分命令行，支持单引号（原样）和双引号（支持 \" \\ \n \t 转义）
+// 引号可以出现在参数中间，如 content:"hello world"
+func tokenize(line string) ([]replToken, error) {
+	var tokens []replToken
+	var current strings.Builder
+	var quoted, inToken bool
+
+	runes := []rune(line)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case r == ' ' || r == '\t':
+			if inToken {
+				tokens = append(tokens, replToken{text: current.String(), quoted: quoted})
+				current.Reset()
+				quoted, inToken = false, false
+			}
+		case r == '\'' || r == '"':
+			inToken, quoted = true, true
+			end := i + 1
+			for ; end < len(runes) && runes[end] != r; end++ {
+				if r == '"' && runes[end] == '\\' && end+1 < len(runes) {
+					end++
+					switch runes[end] {
+					case 'n':
+						current.WriteRune('\n')
+					case 't':
+						current.WriteRune('\t')
+					default:
+						current.WriteRune(runes[end])
+					}
+					continue
+				}
+				current.WriteRune(runes[end])
+			}
+			if end >= len(runes) {
+				return nil, fmt.Errorf("引号未闭合: %s", string(runes[i:]))
+			}
+			i = end
+		default:
+			inToken = true
+			current.WriteRune(r)
+		}
+	}
+	if inToken {
+		tokens = append(tokens, replToken{text: current.String(), quoted: quoted})
+	}
+	return tokens, nil
+}
+
+// parseToolArgs 将 key:value 参数按工具 inputSchema 转换为调用参数
+// 带引号的值按字符串处理（schema 声明为其他类型时按该类型解析引号内的内容）；
+// 未带引号的值按 schema 类型解析，没有类型信息时尝试按JSON解析，失败则作为字符串。
+// key 支持用 . 指定嵌套对象的字段，如 opt.x:1
+func parseToolArgs(schema map[string]interface{}, tokens []replToken) (map[string]interface{}, error) {
+	args := make(map[string]interface{})
+	for _, token := range tokens {
+		key, value, ok := strings.Cut(token.text, ":")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("参数格式应为 key:value: %s", token.text)
+		}
+
+		path := strings.Split(key, ".")
+		converted, err := convertArg(propertySchema(schema, path), value, token.quoted)
+		if err != nil {
+			return nil, fmt.Errorf("参数 %s: %v", key, err)
+		}
+		if err := setPath(args, path, converted); err != nil {
+			return nil, fmt.Errorf("参数 %s: %v", key, err)
+		}
+	}
+	return args, nil
+}
+
+// propertySchema 沿 properties 查找嵌套字段的 schema，未声明时返回 nil
+func propertySchema(schema map[string]interface{}, path []string) map[string]interface{} {
+	for _, name := range path {
+		props, _ := schema["properties"].(map[string]interface{})
+		schema, _ = props[name].(map[string]interface{})
+		if schema == nil {
+			return nil
+		}
+	}
+	return schema
+}
+
+// setPath 按路径写入嵌套对象
+func setPath(args map[string]interface{}, path []string, value interface{}) error {
+	for _, name := range path[:len(path)-1] {
+		next, ok := args[name]
+		if !ok {
+			next = make(map[string]interface{})
+			args[name] = next
+		}
+		obj, ok := next.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s 已被赋值为非对象", name)
+		}
+		args = obj
+	}
+	args[path[len(path)-1]] = value
+	return nil
+}
+
+// convertArg 按 schema 类型转换单个值；声明了多个类型时依次尝试
+func convertArg(schema map[string]interface{}, value string, quoted bool) (interface{}, error) {
+	types := schemaTypeList(schema)
+	if len(types) == 0 {
+		if quoted {
+			return value, nil
+		}
+		if v, err := decodeJSON(value); err == nil {
+			return v, nil
+		}
+		return value, nil
+	}
+
+	var firstErr error
+	for _, t := range types {
+		v, err := convertTyped(t, schema, value)
+		if err == nil {
+			return v, nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	return nil, firstErr
+}
+
+// convertTyped 将值转换为指定的 JSON Schema 类型
+func convertTyped(t string, schema map[string]interface{}, value string) (interface{}, error) {
+	switch t {
+	case "string":
+		return value, nil
+	case "integer":
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("应为整数: %s", value)
+		}
+		return n, nil
+	case "number":
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("应为数字: %s", value)
+		}
+		return f, nil
+	case "boolean":
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("应为 true 或 false: %s", value)
+		}
+		return b, nil
+	case "null":
+		if value != "null" {
+			return nil, fmt.Errorf("应为 null: %s", value)
+		}
+		return nil, nil
+	case "array":
+		// JSON数组，或以逗号分隔的元素列表
+		if strings.HasPrefix(strings.TrimSpace(value), "[") {
+			v, err := decodeJSON(value)
+			if _, ok := v.([]interface{}); err != nil || !ok {
+				return nil, fmt.Errorf("应为JSON数组: %s", value)
+			}
+			return v, nil
+		}
+		items, _ := schema["items"].(map[string]interface{})
+		list := []interface{}{}
+		if value == "" {
+			return list, nil
+		}
+		for _, part := range strings.Split(value, ",") {
+			item, err := convertArg(items, strings.TrimSpace(part), false)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, item)
+		}
+		return list, nil
+	case "object":
+		v, err := decodeJSON(value)
+		if _, ok := v.(map[string]interface{}); err != nil || !ok {
+			return nil, fmt.Errorf("应为JSON对象: %s", value)
+		}
+		return v, nil
+	default:
+		return value, nil
+	}
+}
+
+// decodeJSON 解析单个JSON值，数字保留为 json.Number
+func decodeJSON(value string) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader([]byte(value)))
+	decoder.UseNumber()
+	var v interface{}
+	if err := decoder.Decode(&v); err != nil {
+		return nil, err
+	}
+	if decoder.More() {
+		return nil, fmt.Errorf("多余的内容")
+	}
+	return v, nil
+}
+
+// schemaTypeList 读取 type 关键字，兼容字符串和字符串数组两种写法
+func schemaTypeList(schema map[string]interface{}) []string {
+	switch t := schema["type"].(type) {
+	case string:
+		return []string{t}
+	case []interface{}:
+		types := make([]string, 0, len(t))
+		for _, item := range t {
+			if s, ok := item.(string); ok {
+				types = append(types, s)
+			}
+		}
+		return types
+	}
+	return nil
+}
+
+// schemaParam 工具的一个参数（嵌套字段以 . 连接）
+type schemaParam struct {
+	path     string
+	schema   map[string]interface{}
+	required bool
+}
+
+// schemaParams 按名称列出 inputSchema 中的参数，嵌套对象展开为 a.b 形式
+func schemaParams(schema map[string]interface{}) []schemaParam {
+	var params []schemaParam
+	var walk func(prefix string, schema map[string]interface{}, depth int)
+	walk = func(prefix string, schema map[string]interface{}, depth int) {
+		props, _ := schema["properties"].(map[string]interface{})
+		required := make(map[string]bool)
+		if list, ok := schema["required"].([]interface{}); ok {
+			for _, name := range list {
+				if s, ok := name.(string); ok {
+					required[s] = true
+				}
+			}
+		}
+
+		names := make([]string, 0, len(props))
+		for name := range props {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			prop, _ := props[name].(map[string]interface{})
+			params = append(params, schemaParam{path: prefix + name, schema: prop, required: required[name]})
+			// 嵌套层级有限，避免递归 schema 展开过多
+			if depth < 3 && prop["properties"] != nil {
+				walk(prefix+name+".", prop, depth+1)
+			}
+		}
+	}
+	walk("", schema, 0)
+	return params
+}
+
+// valueCandidates 参数值的补全候选：enum 取值或布尔值
+func valueCandidates(schema map[string]interface{}) []string {
+	var candidates []string
+	if list, ok := schema["enum"].([]interface{}); ok {
+		for _, v := range list {
+			candidates = append(candidates, fmt.Sprint(v))
+		}
+		return candidates
+	}
+	for _, t := range schemaTypeList(schema) {
+		if t == "boolean" {
+			return []string{"true", "false"}
+		}
+	}
+	return nil
+}
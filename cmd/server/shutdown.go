@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"mcp-ai-client/internal/api"
+	"net/http"
+	"time"
+)
+
+// shutdownGrace 强制取消在途请求后，等待处理器写出错误响应以及历史记录写入的时间
+const shutdownGrace = 5 * time.Second
+
+// gracefulShutdown 优雅关闭HTTP服务
+// 停止接受新连接并在 timeout 内等待处理中的请求完成；超时后通知MCP服务器取消仍在执行的调用、
+// 取消请求上下文，再给处理器一小段时间写出响应。最后等待历史记录写入完成，关闭MCP和MySQL连接并导出剩余的span
+func gracefulShutdown(srv *http.Server, cancelRequests context.CancelFunc, handlers *api.Handlers, reloader *configReloader, shutdownTracing func(context.Context) error, timeout time.Duration, logger *slog.Logger) {
+	start := time.Now()
+	logger.Info("开始优雅关闭", "timeout", timeout)
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		n := reloader.cancelPending("服务关闭")
+		logger.Warn("等待超时，已取消未完成的请求", "mcp_calls", n, "error", err)
+		cancelRequests()
+
+		graceCtx, graceCancel := context.WithTimeout(context.Background(), shutdownGrace)
+		defer graceCancel()
+		if err := srv.Shutdown(graceCtx); err != nil {
+			logger.Warn("强制关闭剩余连接", "error", err)
+			srv.Close()
+		}
+	}
+	logger.Info("HTTP服务已停止")
+
+	waitCtx, waitCancel := context.WithTimeout(context.Background(), shutdownGrace)
+	defer waitCancel()
+	if err := handlers.Wait(waitCtx); err != nil {
+		logger.Warn("等待历史记录写入超时", "error", err)
+	}
+
+	reloader.close()
+
+	flushCtx, flushCancel := context.WithTimeout(context.Background(), shutdownGrace)
+	defer flushCancel()
+	if err := shutdownTracing(flushCtx); err != nil {
+		logger.Warn("导出剩余span失败", "error", err)
+	}
+	logger.Info("服务已关闭", "elapsed", time.Since(start).Round(time.Millisecond))
+}
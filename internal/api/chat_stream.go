@@ -0,0 +1,179 @@
+package api
+
+import (
+	"context"
+	"mcp-ai-client/internal/mcp"
+	"mcp-ai-client/internal/service"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxQueuedChunkBytes 等待写出的文本片段总字节数上限
+const maxQueuedChunkBytes = 1 << 20
+
+// chatChunkQueue 有界的文本片段队列
+// 进度回调运行在MCP读取协程中不能阻塞，因此先入队再由处理协程写出；
+// 客户端读取过慢导致积压超过 maxQueuedChunkBytes 时停止入队（不能跳过中间片段），剩余文本在调用结束后由最终结果补发
+type chatChunkQueue struct {
+	mu       sync.Mutex
+	chunks   []string
+	size     int
+	overflow bool
+	signal   chan struct{}
+}
+
+func newChatChunkQueue() *chatChunkQueue {
+	return &chatChunkQueue{signal: make(chan struct{}, 1)}
+}
+
+// push 追加一个片段并唤醒写出协程，积压超限后丢弃此后的全部片段
+func (q *chatChunkQueue) push(chunk string) {
+	q.mu.Lock()
+	if q.overflow || q.size+len(chunk) > maxQueuedChunkBytes {
+		q.overflow = true
+		q.mu.Unlock()
+		return
+	}
+	q.chunks = append(q.chunks, chunk)
+	q.size += len(chunk)
+	q.mu.Unlock()
+	select {
+	case q.signal <- struct{}{}:
+	default:
+	}
+}
+
+// drain 取出当前全部片段
+func (q *chatChunkQueue) drain() []string {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	chunks := q.chunks
+	q.chunks = nil
+	q.size = 0
+	return chunks
+}
+
+// overflowed 是否因积压超限丢弃过片段
+func (q *chatChunkQueue) overflowed() bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.overflow
+}
+
+// MCPChatStreamHandler 5.1 AI对话（SSE流式）
+// 服务端把生成中的文本片段放在 notifications/progress 的 message 中上报，
+// 每个片段以 data 事件推送 {"text": "..."}，结束时发送 done 事件（provider/model/duration）；
+// 服务端不支持流式上报（没有收到任何片段）时，完整回答作为单个 data 事件推送
+func (h *Handlers) MCPChatStreamHandler(c *gin.Context) {
+	state := h.state()
+	start := time.Now()
+
+	if state.mcpClient == nil {
+		respondError(c, http.StatusServiceUnavailable, gin.H{
+			"error": "MCP服务不可用",
+			"tool":  "ai_chat",
+		})
+		return
+	}
+
+	var request service.ChatRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		respondError(c, http.StatusBadRequest, gin.H{
+			"error":   "Invalid request format",
+			"details": err.Error(),
+			"tool":    "ai_chat",
+		})
+		return
+	}
+
+	args := state.aiTools.ChatArgs(request)
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 60*time.Second)
+	defer cancel()
+
+	// 已写出片段后出错时，错误以 error 事件返回
+	c.Set(eventStreamKey, true)
+
+	type outcome struct {
+		result *mcp.ToolCallResult
+		err    error
+	}
+	queue := newChatChunkQueue()
+	done := make(chan outcome, 1)
+
+	go func() {
+		result, err := state.mcpClient.CallTool(ctx, "ai_chat", args, mcp.WithProgress(func(p mcp.Progress) {
+			if p.Message != "" {
+				queue.push(p.Message)
+			}
+		}))
+		done <- outcome{result: result, err: err}
+	}()
+
+	var streamed strings.Builder
+	writeChunks := func() {
+		for _, chunk := range queue.drain() {
+			streamed.WriteString(chunk)
+			c.SSEvent("", gin.H{"text": chunk})
+		}
+		c.Writer.Flush()
+	}
+
+	var o outcome
+	for waiting := true; waiting; {
+		select {
+		case <-queue.signal:
+			writeChunks()
+		case o = <-done:
+			writeChunks()
+			waiting = false
+		}
+	}
+
+	h.recordToolCall(c, state, "ai_chat", args, o.result, o.err, start)
+	if o.err != nil {
+		respondToolError(c, "ai_chat", "AI chat failed", start, o.err)
+		return
+	}
+
+	var final service.ChatResult
+	if err := o.result.DecodeStructured(&final); err != nil || final.Response == "" {
+		final.Response = o.result.Text()
+	}
+	if final.Provider == "" {
+		final.Provider, _ = args["provider"].(string)
+	}
+	if final.Model == "" {
+		final.Model, _ = args["model"].(string)
+	}
+
+	if queue.overflowed() {
+		h.logger.WarnContext(c.Request.Context(), "客户端读取过慢，流式片段积压超限，剩余文本在结束时补发",
+			"tool", "ai_chat", "limit_bytes", maxQueuedChunkBytes)
+	}
+
+	// 补发未通过进度上报（或积压超限被丢弃）的剩余文本；片段与最终回答对不上时以已推送内容为准
+	rest := final.Response
+	if streamed.Len() > 0 {
+		rest = ""
+		if strings.HasPrefix(final.Response, streamed.String()) {
+			rest = final.Response[streamed.Len():]
+		}
+	}
+	if rest != "" {
+		c.SSEvent("", gin.H{"text": rest})
+	}
+
+	c.SSEvent("done", gin.H{
+		"tool":     "ai_chat",
+		"status":   "success",
+		"provider": final.Provider,
+		"model":    final.Model,
+		"duration": time.Since(start).String(),
+	})
+	c.Writer.Flush()
+}
@@ -0,0 +1,241 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"mcp-ai-client/internal/mcp"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// fakeToolCall 假MCP服务端对一次工具调用的处理：先逐条上报 progress，再返回 result
+type fakeToolCall func(params map[string]interface{}) (progress []string, result mcp.ToolCallResult)
+
+// fakeMCP 内存中的MCP服务端，实现 mcp.Transport，记录收到的 tools/call 参数
+type fakeMCP struct {
+	handle fakeToolCall
+	recv   chan []byte
+	closed chan struct{}
+	once   sync.Once
+
+	mu    sync.Mutex
+	calls []map[string]interface{}
+}
+
+// newTestMCPClient 创建连接到假服务端并完成握手的MCP客户端
+func newTestMCPClient(t *testing.T, handle fakeToolCall) (*mcp.MCPClient, *fakeMCP) {
+	t.Helper()
+	server := &fakeMCP{handle: handle, recv: make(chan []byte, 64), closed: make(chan struct{})}
+	dial := func(ctx context.Context) (mcp.Transport, error) { return server, nil }
+	client, err := mcp.NewMCPClient(dial, 5*time.Second, nil, nil)
+	if err != nil {
+		t.Fatalf("NewMCPClient() error = %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+	if err := client.Initialize(context.Background()); err != nil {
+		t.Fatalf("Initialize() error = %v", err)
+	}
+	return client, server
+}
+
+// lastCall 返回最近一次 tools/call 的参数
+func (f *fakeMCP) lastCall() map[string]interface{} {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.calls) == 0 {
+		return nil
+	}
+	return f.calls[len(f.calls)-1]
+}
+
+func (f *fakeMCP) Send(data []byte) error {
+	var msg mcp.MCPMessage
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return err
+	}
+	if msg.ID == nil {
+		return nil
+	}
+	params, _ := msg.Params.(map[string]interface{})
+	reply := mcp.MCPMessage{JSONRPC: "2.0", ID: msg.ID}
+	switch msg.Method {
+	case "initialize":
+		reply.Result = map[string]interface{}{"protocolVersion": "2024-11-05"}
+	case "tools/list":
+		reply.Result = map[string]interface{}{"tools": []mcp.Tool{
+			{Name: "ai_chat", InputSchema: map[string]interface{}{"type": "object"}},
+			{Name: "echo", InputSchema: map[string]interface{}{"type": "object"}},
+		}}
+	case "tools/call":
+		f.mu.Lock()
+		f.calls = append(f.calls, params)
+		f.mu.Unlock()
+		progress, result := f.handle(params)
+		meta, _ := params["_meta"].(map[string]interface{})
+		for i, message := range progress {
+			f.push(mcp.MCPMessage{JSONRPC: "2.0", Method: "notifications/progress", Params: map[string]interface{}{
+				"progressToken": meta["progressToken"],
+				"progress":      i + 1,
+				"message":       message,
+			}})
+		}
+		reply.Result = result
+	default:
+		reply.Error = &mcp.MCPError{Code: -32601, Message: "Method not found: " + msg.Method}
+	}
+	f.push(reply)
+	return nil
+}
+
+func (f *fakeMCP) push(msg mcp.MCPMessage) {
+	data, _ := json.Marshal(msg)
+	f.recv <- data
+}
+
+func (f *fakeMCP) Receive() ([]byte, error) {
+	select {
+	case data := <-f.recv:
+		return data, nil
+	case <-f.closed:
+		return nil, io.EOF
+	}
+}
+
+func (f *fakeMCP) Close() error {
+	f.once.Do(func() { close(f.closed) })
+	return nil
+}
+
+// sseEvent 解析出的一个SSE事件
+type sseEvent struct {
+	Event string
+	Data  map[string]interface{}
+}
+
+// readSSE 解析完整的SSE响应体
+func readSSE(t *testing.T, body io.Reader) []sseEvent {
+	t.Helper()
+	var events []sseEvent
+	var current sseEvent
+	scanner := bufio.NewScanner(body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			if current.Data != nil {
+				events = append(events, current)
+			}
+			current = sseEvent{}
+		case strings.HasPrefix(line, "event:"):
+			current.Event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data:")), &current.Data); err != nil {
+				t.Fatalf("解析事件数据失败: %v: %s", err, line)
+			}
+		}
+	}
+	return events
+}
+
+func TestMCPChatStreamHandler(t *testing.T) {
+	textResult := func(text string) mcp.ToolCallResult {
+		return mcp.ToolCallResult{Content: []mcp.Content{{Type: mcp.ContentText, Text: text}}}
+	}
+	tests := []struct {
+		name     string
+		progress []string
+		result   mcp.ToolCallResult
+		want     []string // data 事件的文本
+	}{
+		{
+			name:     "逐片段推送",
+			progress: []string{"你", "好"},
+			result:   mcp.ToolCallResult{StructuredContent: map[string]interface{}{"response": "你好"}},
+			want:     []string{"你", "好"},
+		},
+		{
+			name:     "补发未上报的剩余文本",
+			progress: []string{"你"},
+			result:   mcp.ToolCallResult{StructuredContent: map[string]interface{}{"response": "你好"}},
+			want:     []string{"你", "好"},
+		},
+		{
+			name:   "没有片段时推送结构化结果中的回答",
+			result: mcp.ToolCallResult{StructuredContent: map[string]interface{}{"response": "完整回答", "model": "m1"}},
+			want:   []string{"完整回答"},
+		},
+		{
+			name:   "没有片段时推送结果文本",
+			result: textResult("纯文本回答"),
+			want:   []string{"纯文本回答"},
+		},
+		{
+			name: "结构化结果没有回答字段时推送结果文本",
+			result: mcp.ToolCallResult{
+				Content:           []mcp.Content{{Type: mcp.ContentText, Text: "文本回答"}},
+				StructuredContent: map[string]interface{}{"model": "m1"},
+			},
+			want: []string{"文本回答"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, _ := newTestMCPClient(t, func(map[string]interface{}) ([]string, mcp.ToolCallResult) {
+				return tt.progress, tt.result
+			})
+			gin.SetMode(gin.TestMode)
+			h := NewHandlers(nil, client, &AIConfig{}, &DatabaseConfig{}, nil)
+			r := gin.New()
+			r.POST("/chat/stream", h.MCPChatStreamHandler)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/chat/stream", strings.NewReader(`{"prompt":"hi"}`))
+			req.Header.Set("Content-Type", "application/json")
+			r.ServeHTTP(w, req)
+
+			events := readSSE(t, w.Body)
+			if len(events) == 0 || events[len(events)-1].Event != "done" {
+				t.Fatalf("events = %+v, want 以 done 事件结束", events)
+			}
+			var got []string
+			for _, e := range events[:len(events)-1] {
+				if e.Event != "" {
+					t.Errorf("事件类型 = %q, want data 事件", e.Event)
+				}
+				text, _ := e.Data["text"].(string)
+				got = append(got, text)
+			}
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("data = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChatChunkQueueBounded(t *testing.T) {
+	q := newChatChunkQueue()
+	chunk := strings.Repeat("x", maxQueuedChunkBytes/2)
+	q.push(chunk)
+	q.push(chunk)
+	q.push("y") // 超过上限
+	q.push("z") // 超限后不再入队，避免跳过中间片段
+
+	if got := q.drain(); len(got) != 2 {
+		t.Errorf("drain() = %d 个片段, want 2", len(got))
+	}
+	if !q.overflowed() {
+		t.Error("overflowed() = false, want true")
+	}
+	q.push("w")
+	if got := q.drain(); len(got) != 0 {
+		t.Errorf("超限后 drain() = %q, want 空", got)
+	}
+}
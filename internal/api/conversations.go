@@ -0,0 +1,233 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"io"
+	"mcp-ai-client/internal/service"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ===== 多轮对话API =====
+
+// roleLabels 历史拼接进 prompt 时使用的角色名称
+var roleLabels = map[string]string{
+	service.RoleSystem:    "系统",
+	service.RoleUser:      "用户",
+	service.RoleAssistant: "助手",
+}
+
+// CreateConversationHandler 创建会话
+// 请求体可选：title、system_prompt，以及会话默认的 provider、model
+func (h *Handlers) CreateConversationHandler(c *gin.Context) {
+	var request struct {
+		Title        string `json:"title"`
+		SystemPrompt string `json:"system_prompt"`
+		Provider     string `json:"provider"`
+		Model        string `json:"model"`
+	}
+	// 请求体可省略；以 io.EOF 判断为空，分块传输的请求没有 ContentLength
+	if err := c.ShouldBindJSON(&request); err != nil && !errors.Is(err, io.EOF) {
+		respondError(c, http.StatusBadRequest, gin.H{
+			"error":   "Invalid request format",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	conv, err := h.conversationService.Create(c.Request.Context(), request.Title, request.SystemPrompt, request.Provider, request.Model)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, gin.H{
+			"error":   "Create conversation failed",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, conv)
+}
+
+// GetConversationHandler 返回会话及完整消息历史
+func (h *Handlers) GetConversationHandler(c *gin.Context) {
+	conv, err := h.conversationService.Get(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondConversationError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, conv)
+}
+
+// DeleteConversationHandler 删除会话
+func (h *Handlers) DeleteConversationHandler(c *gin.Context) {
+	id := c.Param("id")
+	if err := h.conversationService.Delete(c.Request.Context(), id); err != nil {
+		respondConversationError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"conversation_id": id,
+		"status":          "deleted",
+	})
+}
+
+// SendConversationMessageHandler 在会话中发送一条消息
+// 裁剪后的历史随本轮消息一起发给 ai_chat；调用成功后问答才写入历史，失败可直接重试
+func (h *Handlers) SendConversationMessageHandler(c *gin.Context) {
+	state := h.state()
+	start := time.Now()
+	id := c.Param("id")
+
+	if state.mcpClient == nil {
+		respondError(c, http.StatusServiceUnavailable, gin.H{
+			"error": "MCP服务不可用",
+			"tool":  "ai_chat",
+		})
+		return
+	}
+
+	var request struct {
+		Content     string  `json:"content" binding:"required"`
+		Provider    string  `json:"provider"`
+		Model       string  `json:"model"`
+		MaxTokens   int     `json:"max_tokens"`
+		Temperature float64 `json:"temperature"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		respondError(c, http.StatusBadRequest, gin.H{
+			"error":   "Invalid request format",
+			"details": err.Error(),
+			"tool":    "ai_chat",
+		})
+		return
+	}
+
+	conv, err := h.conversationService.Get(c.Request.Context(), id)
+	if err != nil {
+		respondConversationError(c, err)
+		return
+	}
+	// 本轮的工具调用记录关联到会话
+	c.Set(conversationIDKey, id)
+
+	// 未指定时沿用会话创建时的 provider/model
+	chat := service.ChatRequest{
+		Prompt:      request.Content,
+		Provider:    request.Provider,
+		Model:       request.Model,
+		MaxTokens:   request.MaxTokens,
+		Temperature: request.Temperature,
+	}
+	if chat.Provider == "" {
+		chat.Provider = conv.Provider
+	}
+	if chat.Model == "" {
+		chat.Model = conv.Model
+	}
+
+	userMessage := service.Message{Role: service.RoleUser, Content: request.Content, CreatedAt: start}
+	messages, truncated := h.conversationService.BuildContext(conv, userMessage)
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 60*time.Second)
+	defer cancel()
+
+	args := state.aiTools.ChatArgs(chat)
+	h.applyChatHistory(ctx, state, args, messages)
+
+	result, err := h.callTool(c, ctx, state, "ai_chat", args)
+	if err != nil {
+		respondToolError(c, "ai_chat", "AI chat failed", start, err)
+		return
+	}
+
+	var reply service.ChatResult
+	if err := result.DecodeStructured(&reply); err != nil {
+		reply = service.ChatResult{Response: result.Text()}
+	}
+	if reply.Provider == "" {
+		reply.Provider, _ = args["provider"].(string)
+	}
+	if reply.Model == "" {
+		reply.Model, _ = args["model"].(string)
+	}
+
+	assistantMessage := service.Message{Role: service.RoleAssistant, Content: reply.Response, CreatedAt: time.Now()}
+	// 回答已生成，即使请求方已断开也写入历史
+	if err := h.conversationService.Append(context.WithoutCancel(ctx), id, userMessage, assistantMessage); err != nil {
+		// 调用期间会话被删除
+		respondConversationError(c, err)
+		return
+	}
+
+	responseData := map[string]interface{}{
+		"conversation_id":  id,
+		"tool":             "ai_chat",
+		"status":           "success",
+		"message":          assistantMessage,
+		"provider":         reply.Provider,
+		"model":            reply.Model,
+		"context_messages": len(messages),
+		"truncated":        truncated,
+		"duration":         time.Since(start).String(),
+	}
+	service.AppendResultContent(responseData, result)
+	respond(c, http.StatusOK, responseData)
+}
+
+// applyChatHistory 将会话上下文放入 ai_chat 参数，本轮用户消息只通过 prompt 传递
+// 工具声明了 messages 参数时以消息列表传之前的对话，否则把之前的对话拼接进 prompt
+func (h *Handlers) applyChatHistory(ctx context.Context, state *handlerState, args map[string]interface{}, messages []service.Message) {
+	// 只有本轮消息时无需附带上下文
+	if len(messages) <= 1 {
+		return
+	}
+	history := messages[:len(messages)-1]
+
+	if tool, ok, err := state.mcpClient.GetTool(ctx, "ai_chat"); err == nil && ok {
+		if props, _ := tool.InputSchema["properties"].(map[string]interface{}); props != nil {
+			if _, ok := props["messages"]; ok {
+				list := make([]map[string]string, 0, len(history))
+				for _, msg := range history {
+					list = append(list, map[string]string{"role": msg.Role, "content": msg.Content})
+				}
+				args["messages"] = list
+				return
+			}
+		}
+	}
+
+	prompt, ok := args["prompt"].(string)
+	if !ok {
+		h.logger.WarnContext(ctx, "ai_chat 参数中的 prompt 不是字符串，未附带会话上下文", "history_messages", len(history))
+		return
+	}
+	var transcript strings.Builder
+	transcript.WriteString("以下是之前的对话：\n")
+	for _, msg := range history {
+		transcript.WriteString(roleLabels[msg.Role])
+		transcript.WriteString(": ")
+		transcript.WriteString(msg.Content)
+		transcript.WriteString("\n")
+	}
+	transcript.WriteString("\n用户: ")
+	transcript.WriteString(prompt)
+	args["prompt"] = transcript.String()
+}
+
+// respondConversationError 返回会话操作错误
+func respondConversationError(c *gin.Context, err error) {
+	if errors.Is(err, service.ErrConversationNotFound) {
+		respondError(c, http.StatusNotFound, gin.H{
+			"error":           "Conversation not found",
+			"conversation_id": c.Param("id"),
+		})
+		return
+	}
+	respondError(c, http.StatusInternalServerError, gin.H{
+		"error":   "Conversation operation failed",
+		"details": err.Error(),
+	})
+}
@@ -0,0 +1,87 @@
+package api
+
+import (
+	"errors"
+	"mcp-ai-client/internal/mcp"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// StatusClientClosedRequest 客户端在响应前断开（沿用 nginx 的 499 约定）
+const StatusClientClosedRequest = 499
+
+// mcpErrorStatus 将MCP调用错误按分类映射为HTTP状态码
+//
+//	validation → 400  参数未通过 inputSchema 校验
+//	tool       → 422  工具已执行但返回 isError
+//	protocol   → 502  服务端返回 JSON-RPC 错误（参数错误 -32602 视为 400）
+//	timeout    → 504  等待响应超时
+//	transport  → 502  连接失败或断开
+//	canceled   → 499  调用方已取消
+func mcpErrorStatus(err error) int {
+	switch mcp.ClassifyError(err) {
+	case mcp.ErrorClassValidation:
+		return http.StatusBadRequest
+	case mcp.ErrorClassTool:
+		return http.StatusUnprocessableEntity
+	case mcp.ErrorClassProtocol:
+		var protocolErr *mcp.MCPError
+		if errors.As(err, &protocolErr) && protocolErr.Code == mcp.CodeInvalidParams {
+			return http.StatusBadRequest
+		}
+		return http.StatusBadGateway
+	case mcp.ErrorClassTimeout:
+		return http.StatusGatewayTimeout
+	case mcp.ErrorClassTransport:
+		return http.StatusBadGateway
+	case mcp.ErrorClassCanceled:
+		return StatusClientClosedRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// respondToolError 按错误分类写出MCP工具调用失败的响应
+// message 为面向调用方的简要说明，例如 "AI chat failed"
+func respondToolError(c *gin.Context, toolName string, message string, start time.Time, err error) {
+	class := mcp.ClassifyError(err)
+	body := gin.H{
+		"error":       message,
+		"details":     err.Error(),
+		"error_class": class,
+		"duration":    time.Since(start).String(),
+		"tool":        toolName,
+	}
+
+	var (
+		validationErr *mcp.ValidationError
+		toolErr       *mcp.ToolError
+		protocolErr   *mcp.MCPError
+	)
+	switch {
+	case errors.As(err, &validationErr):
+		body["error"] = "Invalid arguments"
+		body["details"] = validationErr.Error()
+		body["fields"] = validationErr.Errors
+	case errors.As(err, &toolErr):
+		// 工具自身的错误信息在结果内容中，原样返回便于排查
+		body["result"] = toolErr.Result
+	case errors.As(err, &protocolErr):
+		body["code"] = protocolErr.Code
+		if protocolErr.Data != nil {
+			body["data"] = protocolErr.Data
+		}
+	}
+
+	respondError(c, mcpErrorStatus(err), body)
+}
+
+// respondError 写出错误响应，响应体附带 request_id 便于调用方反馈问题时定位日志
+func respondError(c *gin.Context, status int, body gin.H) {
+	if id := RequestID(c); id != "" {
+		body["request_id"] = id
+	}
+	respond(c, status, body)
+}
@@ -0,0 +1,164 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// healthCheckTimeout 单个依赖检查的超时时间
+const healthCheckTimeout = 2 * time.Second
+
+// 依赖状态
+const (
+	DependencyUp            = "up"
+	DependencyDown          = "down"
+	DependencyNotConfigured = "not_configured"
+)
+
+// DependencyStatus 一个依赖的检查结果
+type DependencyStatus struct {
+	Status      string     `json:"status"`
+	Required    bool       `json:"required"`
+	LatencyMs   float64    `json:"latency_ms"`
+	Error       string     `json:"error,omitempty"`
+	State       string     `json:"state,omitempty"`      // MCP连接状态
+	LastError   string     `json:"last_error,omitempty"` // 最近一次检查失败的原因，恢复后仍保留
+	LastErrorAt *time.Time `json:"last_error_at,omitempty"`
+}
+
+// healthTracker 记录各依赖最近一次检查失败
+type healthTracker struct {
+	mu         sync.Mutex
+	lastErrors map[string]dependencyError
+}
+
+// dependencyError 依赖检查失败的记录
+type dependencyError struct {
+	message string
+	at      time.Time
+}
+
+// record 记录检查结果并填充最近一次失败
+func (t *healthTracker) record(name string, status *DependencyStatus) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if status.Error != "" {
+		if t.lastErrors == nil {
+			t.lastErrors = make(map[string]dependencyError)
+		}
+		t.lastErrors[name] = dependencyError{message: status.Error, at: time.Now()}
+	}
+	if last, ok := t.lastErrors[name]; ok {
+		at := last.at
+		status.LastError, status.LastErrorAt = last.message, &at
+	}
+}
+
+// checkDependencies 并发检查MySQL（PingContext）和MCP（ping 请求），返回各依赖状态以及必需依赖是否全部可用
+func (h *Handlers) checkDependencies(ctx context.Context) (map[string]*DependencyStatus, bool) {
+	state := h.state()
+	checks := make(map[string]func(context.Context) error)
+	results := map[string]*DependencyStatus{
+		"mysql": {Status: DependencyNotConfigured},
+		"mcp":   {Status: DependencyNotConfigured},
+	}
+	if state.mysqlClient != nil {
+		checks["mysql"] = state.mysqlClient.Ping
+	}
+	if state.mcpClient != nil {
+		checks["mcp"] = state.mcpClient.Ping
+		results["mcp"].State = state.mcpClient.State().String()
+	}
+
+	var wg sync.WaitGroup
+	for name, check := range checks {
+		wg.Add(1)
+		go func(status *DependencyStatus, check func(context.Context) error) {
+			defer wg.Done()
+			checkCtx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+			defer cancel()
+
+			start := time.Now()
+			err := check(checkCtx)
+			status.LatencyMs = float64(time.Since(start).Microseconds()) / 1000
+			status.Required = true
+			status.Status = DependencyUp
+			if err != nil {
+				status.Status = DependencyDown
+				status.Error = err.Error()
+			}
+		}(results[name], check)
+	}
+	wg.Wait()
+
+	ready := true
+	for name, status := range results {
+		h.health.record(name, status)
+		if status.Required && status.Status != DependencyUp {
+			ready = false
+		}
+	}
+	return results, ready
+}
+
+// ===== 健康检查 =====
+
+// LivenessHandler 存活检查：进程能处理请求即返回200，不检查依赖
+func (h *Handlers) LivenessHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"status":    "alive",
+		"uptime":    time.Since(h.startedAt).Round(time.Second).String(),
+		"timestamp": time.Now().Format(time.RFC3339),
+	})
+}
+
+// ReadinessHandler 就绪检查：MySQL和MCP都可用时返回200，否则返回503
+func (h *Handlers) ReadinessHandler(c *gin.Context) {
+	checks, ready := h.checkDependencies(c.Request.Context())
+
+	status, code := "ready", http.StatusOK
+	if !ready {
+		status, code = "not_ready", http.StatusServiceUnavailable
+	}
+	c.JSON(code, gin.H{
+		"status":    status,
+		"checks":    checks,
+		"timestamp": time.Now().Format(time.RFC3339),
+	})
+}
+
+// HealthCheck 健康检查：依赖检查同 ReadinessHandler，并附带服务信息和MCP工具列表
+func (h *Handlers) HealthCheck(c *gin.Context) {
+	checks, ready := h.checkDependencies(c.Request.Context())
+
+	status := gin.H{
+		"status":    "healthy",
+		"timestamp": time.Now().Format(time.RFC3339),
+		"service":   "MCP AI Client - 简化版",
+		"version":   "2.0.0",
+		"mysql":     checks["mysql"].Status,
+		"mcp":       checks["mcp"].Status,
+		"checks":    checks,
+	}
+
+	// 报告服务端实际提供的工具（来自 tools/list 缓存）
+	if mcpClient := h.state().mcpClient; mcpClient != nil {
+		tools := mcpClient.CachedTools()
+		names := make([]string, 0, len(tools))
+		for _, tool := range tools {
+			names = append(names, tool.Name)
+		}
+		status["mcp_tools"] = names
+	}
+
+	code := http.StatusOK
+	if !ready {
+		status["status"] = "unhealthy"
+		code = http.StatusServiceUnavailable
+	}
+	c.JSON(code, status)
+}
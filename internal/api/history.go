@@ -0,0 +1,259 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mcp-ai-client/internal/database"
+	"mcp-ai-client/internal/logging"
+	"mcp-ai-client/internal/mcp"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// conversationIDKey 当前请求所属会话，用于关联工具调用记录
+const conversationIDKey = "conversation_id"
+
+// historyWriteTimeout 写入一条历史记录的超时时间
+const historyWriteTimeout = 5 * time.Second
+
+// recordToolCall 记录工具调用日志，并异步写入 state 中的工具调用记录（日志和记录中的参数都经过脱敏），写入失败只记录日志
+func (h *Handlers) recordToolCall(c *gin.Context, state *handlerState, toolName string, args map[string]interface{}, result *mcp.ToolCallResult, err error, start time.Time) {
+	reqCtx := c.Request.Context()
+	if err != nil {
+		h.logger.WarnContext(reqCtx, "工具调用失败", "tool", toolName, "duration", time.Since(start),
+			"error_class", mcp.ClassifyError(err), "error", err)
+	} else {
+		h.logger.InfoContext(reqCtx, "工具调用完成", "tool", toolName, "duration", time.Since(start))
+	}
+	h.logger.DebugContext(reqCtx, "工具调用参数", "tool", toolName, "arguments", args)
+
+	history := state.history
+	if history == nil {
+		return
+	}
+
+	inv := &database.ToolInvocation{
+		Tool:           toolName,
+		Status:         "success",
+		ConversationID: c.GetString(conversationIDKey),
+		DurationMs:     time.Since(start).Milliseconds(),
+		CreatedAt:      start,
+	}
+	inv.Provider, _ = args["provider"].(string)
+	inv.Model, _ = args["model"].(string)
+	// 与日志相同的规则脱敏，auth_info 等凭据不写入数据库
+	if data, marshalErr := json.Marshal(logging.Redact(args)); marshalErr == nil {
+		inv.Arguments = string(data)
+	}
+
+	if err != nil {
+		inv.Status = "error"
+		inv.ErrorClass = mcp.ClassifyError(err)
+		inv.ErrorMessage = err.Error()
+		// 工具执行失败时结果中包含错误详情
+		var toolErr *mcp.ToolError
+		if errors.As(err, &toolErr) {
+			result = toolErr.Result
+		}
+	}
+	if result != nil {
+		if data, marshalErr := json.Marshal(result); marshalErr == nil {
+			inv.Result = string(data)
+		}
+	}
+
+	h.background.Add(1)
+	go func() {
+		defer h.background.Done()
+		ctx, cancel := context.WithTimeout(context.Background(), historyWriteTimeout)
+		defer cancel()
+		if err := history.InsertToolInvocation(ctx, inv); err != nil {
+			h.logger.ErrorContext(reqCtx, "写入工具调用记录失败", "tool", toolName, "error", err)
+		}
+	}()
+}
+
+// ===== 历史记录查询API =====
+
+// ListToolCallsHandler 分页查询工具调用记录
+// 过滤参数：tool、status(success/error)、error_class、provider、model、conversation_id、since、until(RFC3339)、limit、offset
+func (h *Handlers) ListToolCallsHandler(c *gin.Context) {
+	history := h.requireHistory(c)
+	if history == nil {
+		return
+	}
+
+	filter := database.ToolInvocationFilter{
+		Tool:           c.Query("tool"),
+		Status:         c.Query("status"),
+		ErrorClass:     c.Query("error_class"),
+		Provider:       c.Query("provider"),
+		Model:          c.Query("model"),
+		ConversationID: c.Query("conversation_id"),
+	}
+	var err error
+	if filter.Since, filter.Until, filter.Limit, filter.Offset, err = parseHistoryQuery(c); err != nil {
+		respondInvalidQuery(c, err)
+		return
+	}
+
+	invocations, total, err := history.ListToolInvocations(c.Request.Context(), filter)
+	if err != nil {
+		respondHistoryError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"tool_calls": invocations,
+		"count":      len(invocations),
+		"total":      total,
+	})
+}
+
+// GetToolCallHandler 查询单条工具调用记录
+func (h *Handlers) GetToolCallHandler(c *gin.Context) {
+	history := h.requireHistory(c)
+	if history == nil {
+		return
+	}
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		respondInvalidQuery(c, fmt.Errorf("无效的记录ID: %s", c.Param("id")))
+		return
+	}
+
+	inv, err := history.GetToolInvocation(c.Request.Context(), id)
+	if err == sql.ErrNoRows {
+		respondError(c, http.StatusNotFound, gin.H{
+			"error": "Tool call not found",
+			"id":    id,
+		})
+		return
+	}
+	if err != nil {
+		respondHistoryError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, inv)
+}
+
+// ListConversationRecordsHandler 分页查询会话记录
+// 过滤参数：include_deleted=true、since、until(按最近活跃时间)、limit、offset
+func (h *Handlers) ListConversationRecordsHandler(c *gin.Context) {
+	history := h.requireHistory(c)
+	if history == nil {
+		return
+	}
+
+	filter := database.ConversationFilter{
+		IncludeDeleted: c.Query("include_deleted") == "true",
+	}
+	var err error
+	if filter.Since, filter.Until, filter.Limit, filter.Offset, err = parseHistoryQuery(c); err != nil {
+		respondInvalidQuery(c, err)
+		return
+	}
+
+	conversations, total, err := history.ListConversations(c.Request.Context(), filter)
+	if err != nil {
+		respondHistoryError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"conversations": conversations,
+		"count":         len(conversations),
+		"total":         total,
+	})
+}
+
+// ListMessageRecordsHandler 分页查询会话消息（含已删除会话）
+// 过滤参数：conversation_id、role、q(内容关键词)、since、until、limit、offset
+func (h *Handlers) ListMessageRecordsHandler(c *gin.Context) {
+	history := h.requireHistory(c)
+	if history == nil {
+		return
+	}
+
+	filter := database.MessageFilter{
+		ConversationID: c.Query("conversation_id"),
+		Role:           c.Query("role"),
+		Keyword:        c.Query("q"),
+	}
+	var err error
+	if filter.Since, filter.Until, filter.Limit, filter.Offset, err = parseHistoryQuery(c); err != nil {
+		respondInvalidQuery(c, err)
+		return
+	}
+
+	messages, total, err := history.ListMessages(c.Request.Context(), filter)
+	if err != nil {
+		respondHistoryError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"messages": messages,
+		"count":    len(messages),
+		"total":    total,
+	})
+}
+
+// requireHistory 返回历史记录存储，未启用时返回503并返回 nil
+func (h *Handlers) requireHistory(c *gin.Context) *database.MySQLClient {
+	if history := h.state().history; history != nil {
+		return history
+	}
+	respondError(c, http.StatusServiceUnavailable, gin.H{
+		"error": "历史记录未启用",
+	})
+	return nil
+}
+
+// parseHistoryQuery 解析通用的时间区间和分页参数
+func parseHistoryQuery(c *gin.Context) (since, until time.Time, limit, offset int, err error) {
+	if v := c.Query("since"); v != "" {
+		if since, err = time.Parse(time.RFC3339, v); err != nil {
+			return since, until, 0, 0, fmt.Errorf("since 必须是RFC3339时间: %v", err)
+		}
+	}
+	if v := c.Query("until"); v != "" {
+		if until, err = time.Parse(time.RFC3339, v); err != nil {
+			return since, until, 0, 0, fmt.Errorf("until 必须是RFC3339时间: %v", err)
+		}
+	}
+	if v := c.Query("limit"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil {
+			return since, until, 0, 0, fmt.Errorf("limit 必须是整数: %v", err)
+		}
+	}
+	if v := c.Query("offset"); v != "" {
+		if offset, err = strconv.Atoi(v); err != nil {
+			return since, until, 0, 0, fmt.Errorf("offset 必须是整数: %v", err)
+		}
+	}
+	return since, until, limit, offset, nil
+}
+
+// respondInvalidQuery 返回查询参数错误
+func respondInvalidQuery(c *gin.Context, err error) {
+	respondError(c, http.StatusBadRequest, gin.H{
+		"error":   "Invalid query",
+		"details": err.Error(),
+	})
+}
+
+// respondHistoryError 返回历史记录查询失败
+func respondHistoryError(c *gin.Context, err error) {
+	respondError(c, http.StatusInternalServerError, gin.H{
+		"error":   "History query failed",
+		"details": err.Error(),
+	})
+}
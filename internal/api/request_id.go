@@ -0,0 +1,68 @@
+package api
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"mcp-ai-client/internal/logging"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// RequestIDHeader 携带请求ID的请求头和响应头
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength 接受的调用方请求ID最大长度
+const maxRequestIDLength = 128
+
+// attrRequestID span 上记录请求ID的属性
+const attrRequestID = attribute.Key("http.request.id")
+
+// RequestIDMiddleware 为每个请求确定请求ID：沿用调用方的 X-Request-ID（格式不合法时忽略），没有则生成
+// 请求ID写入响应头和错误响应体（request_id 字段），记录在 c.Request.Context() 中，
+// 以该 context 记录的日志都带 request_id 字段，MCP工具调用时通过 _meta 传给服务端；
+// 需注册在 tracing.GinMiddleware 之后，才能同时记录到服务端span上
+func RequestIDMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+
+		ctx := logging.WithRequestID(c.Request.Context(), id)
+		trace.SpanFromContext(ctx).SetAttributes(attrRequestID.String(id))
+		c.Request = c.Request.WithContext(ctx)
+		c.Header(RequestIDHeader, id)
+		c.Next()
+	}
+}
+
+// RequestID 返回当前请求的ID，未经过 RequestIDMiddleware 时返回空字符串
+func RequestID(c *gin.Context) string {
+	return logging.RequestID(c.Request.Context())
+}
+
+// validRequestID 调用方提供的请求ID是否可用：非空、不超过 maxRequestIDLength，
+// 且只含字母、数字和 -_.:/+=，避免把控制字符或任意文本写进日志和响应头
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		switch ch := id[i]; {
+		case ch >= 'a' && ch <= 'z', ch >= 'A' && ch <= 'Z', ch >= '0' && ch <= '9':
+		case ch == '-', ch == '_', ch == '.', ch == ':', ch == '/', ch == '+', ch == '=':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// newRequestID 生成随机请求ID（32位十六进制）
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b) // Go 1.24 起 crypto/rand 失败时直接终止程序，不返回错误
+	return hex.EncodeToString(b)
+}
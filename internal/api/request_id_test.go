@@ -0,0 +1,113 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"mcp-ai-client/internal/logging"
+	"mcp-ai-client/internal/mcp"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// generatedRequestID newRequestID 生成的ID格式
+var generatedRequestID = regexp.MustCompile(`^[0-9a-f]{32}$`)
+
+func TestRequestIDMiddleware(t *testing.T) {
+	tests := []struct {
+		name     string
+		incoming string
+		want     string // 为空时应生成新ID
+	}{
+		{name: "沿用调用方的ID", incoming: "req-123:abc", want: "req-123:abc"},
+		{name: "没有ID时生成", incoming: ""},
+		{name: "含非法字符时重新生成", incoming: "bad id\r\n"},
+		{name: "超长时重新生成", incoming: strings.Repeat("a", maxRequestIDLength+1)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, server := newTestMCPClient(t, func(map[string]interface{}) ([]string, mcp.ToolCallResult) {
+				return nil, mcp.ToolCallResult{StructuredContent: map[string]interface{}{"response": "你好"}}
+			})
+			var logs bytes.Buffer
+			logger, _, err := logging.New(&logs, logging.Config{Format: logging.FormatJSON})
+			if err != nil {
+				t.Fatalf("logging.New() error = %v", err)
+			}
+
+			gin.SetMode(gin.TestMode)
+			h := NewHandlers(nil, client, &AIConfig{}, &DatabaseConfig{}, logger)
+			r := gin.New()
+			r.Use(RequestIDMiddleware())
+			r.POST("/chat", h.MCPChatHandler)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/chat", strings.NewReader(`{"prompt":"hi"}`))
+			req.Header.Set("Content-Type", "application/json")
+			if tt.incoming != "" {
+				req.Header.Set(RequestIDHeader, tt.incoming)
+			}
+			r.ServeHTTP(w, req)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusOK, w.Body)
+			}
+			id := w.Header().Get(RequestIDHeader)
+			if tt.want != "" && id != tt.want {
+				t.Errorf("%s = %q, want %q", RequestIDHeader, id, tt.want)
+			}
+			if tt.want == "" && !generatedRequestID.MatchString(id) {
+				t.Errorf("%s = %q, want 生成的32位十六进制ID", RequestIDHeader, id)
+			}
+
+			meta, _ := server.lastCall()["_meta"].(map[string]interface{})
+			if meta["x-request-id"] != id {
+				t.Errorf("_meta[x-request-id] = %v, want %q", meta["x-request-id"], id)
+			}
+
+			var records int
+			scanner := bufio.NewScanner(&logs)
+			for scanner.Scan() {
+				var record map[string]interface{}
+				if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+					t.Fatalf("解析日志失败: %v: %s", err, scanner.Text())
+				}
+				records++
+				if record["request_id"] != id {
+					t.Errorf("日志 %q 的 request_id = %v, want %q", record["msg"], record["request_id"], id)
+				}
+			}
+			if records == 0 {
+				t.Error("没有记录日志")
+			}
+		})
+	}
+}
+
+func TestRequestIDInErrorResponse(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	h := NewHandlers(nil, nil, &AIConfig{}, &DatabaseConfig{}, nil)
+	r := gin.New()
+	r.Use(RequestIDMiddleware())
+	r.POST("/chat", h.MCPChatHandler)
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/chat", strings.NewReader(`{"prompt":"hi"}`))
+	req.Header.Set(RequestIDHeader, "req-err")
+	r.ServeHTTP(w, req)
+
+	var body struct {
+		RequestID string `json:"request_id"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("解析响应失败: %v: %s", err, w.Body)
+	}
+	if body.RequestID != "req-err" {
+		t.Errorf("request_id = %q, want %q", body.RequestID, "req-err")
+	}
+}
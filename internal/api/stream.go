@@ -0,0 +1,83 @@
+package api
+
+import (
+	"context"
+	"mcp-ai-client/internal/mcp"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// eventStreamKey 标记当前请求以SSE推送进度
+const eventStreamKey = "mcp_event_stream"
+
+// progressBuffer 未及时写出的进度事件缓冲，溢出时丢弃新事件
+const progressBuffer = 32
+
+// wantsEventStream 请求方通过 Accept: text/event-stream 或 stream=true 订阅进度
+func wantsEventStream(c *gin.Context) bool {
+	return strings.Contains(c.GetHeader("Accept"), "text/event-stream") || c.Query("stream") == "true"
+}
+
+// callTool 调用MCP工具并写入调用记录；订阅进度的请求会在等待期间以 progress 事件推送服务端进度，
+// 最终结果由 respond 作为 result 或 error 事件写出；state 为处理器开始时取得的快照，整个请求使用同一组连接和配置
+func (h *Handlers) callTool(c *gin.Context, ctx context.Context, state *handlerState, toolName string, args map[string]interface{}) (result *mcp.ToolCallResult, err error) {
+	start := time.Now()
+	defer func() {
+		h.recordToolCall(c, state, toolName, args, result, err, start)
+	}()
+
+	if !wantsEventStream(c) {
+		return state.mcpClient.CallTool(ctx, toolName, args)
+	}
+	c.Set(eventStreamKey, true)
+
+	type outcome struct {
+		result *mcp.ToolCallResult
+		err    error
+	}
+	progress := make(chan mcp.Progress, progressBuffer)
+	done := make(chan outcome, 1)
+
+	go func() {
+		result, err := state.mcpClient.CallTool(ctx, toolName, args, mcp.WithProgress(func(p mcp.Progress) {
+			// 进度回调运行在MCP读取协程中，不能阻塞
+			select {
+			case progress <- p:
+			default:
+			}
+		}))
+		done <- outcome{result: result, err: err}
+	}()
+
+	// gin 的 ResponseWriter 不支持并发写，事件统一在当前协程写出
+	for {
+		select {
+		case p := <-progress:
+			c.SSEvent("progress", p)
+			c.Writer.Flush()
+		case o := <-done:
+			for len(progress) > 0 {
+				c.SSEvent("progress", <-progress)
+			}
+			return o.result, o.err
+		}
+	}
+}
+
+// respond 写出最终响应
+// 已经推送过进度事件时状态码无法再修改，结果以 result 事件（失败时为 error 事件）写出；
+// 否则按普通JSON响应返回
+func respond(c *gin.Context, status int, body interface{}) {
+	if c.GetBool(eventStreamKey) && c.Writer.Written() {
+		event := "result"
+		if status >= 400 {
+			event = "error"
+		}
+		c.SSEvent(event, body)
+		c.Writer.Flush()
+		return
+	}
+	c.JSON(status, body)
+}
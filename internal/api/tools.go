@@ -0,0 +1,137 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mcp-ai-client/internal/mcp"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ===== 通用MCP工具API =====
+
+// ListToolsHandler 列出MCP服务端提供的工具目录
+// 查询参数 refresh=true 时忽略缓存重新拉取
+func (h *Handlers) ListToolsHandler(c *gin.Context) {
+	state := h.state()
+	if state.mcpClient == nil {
+		respondError(c, http.StatusServiceUnavailable, gin.H{
+			"error": "MCP服务不可用",
+		})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+	defer cancel()
+
+	var err error
+	if c.Query("refresh") == "true" {
+		_, err = state.mcpClient.RefreshTools(ctx)
+	}
+	tools, listErr := state.mcpClient.ListTools(ctx)
+	if err == nil {
+		err = listErr
+	}
+	if err != nil {
+		respondError(c, mcpErrorStatus(err), gin.H{
+			"error":       "List tools failed",
+			"details":     err.Error(),
+			"error_class": mcp.ClassifyError(err),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"tools":     tools,
+		"count":     len(tools),
+		"timestamp": time.Now().Format(time.RFC3339),
+	})
+}
+
+// CallToolHandler 按名称调用任意MCP工具
+// 请求体即工具参数对象，CallTool 会在发送前按工具的 inputSchema 校验
+func (h *Handlers) CallToolHandler(c *gin.Context) {
+	state := h.state()
+	start := time.Now()
+	toolName := c.Param("name")
+
+	if state.mcpClient == nil {
+		respondError(c, http.StatusServiceUnavailable, gin.H{
+			"error": "MCP服务不可用",
+			"tool":  toolName,
+		})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 120*time.Second)
+	defer cancel()
+
+	_, ok, err := state.mcpClient.GetTool(ctx, toolName)
+	if err != nil {
+		respondError(c, mcpErrorStatus(err), gin.H{
+			"error":       "List tools failed",
+			"details":     err.Error(),
+			"error_class": mcp.ClassifyError(err),
+			"tool":        toolName,
+		})
+		return
+	}
+	if !ok {
+		respondError(c, http.StatusNotFound, gin.H{
+			"error": "Tool not found",
+			"tool":  toolName,
+		})
+		return
+	}
+
+	args, err := bindToolArguments(c)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, gin.H{
+			"error":   "Invalid request format",
+			"details": err.Error(),
+			"tool":    toolName,
+		})
+		return
+	}
+
+	result, err := h.callTool(c, ctx, state, toolName, args)
+	if err != nil {
+		respondToolError(c, toolName, "Tool call failed", start, err)
+		return
+	}
+
+	respond(c, http.StatusOK, gin.H{
+		"tool":     toolName,
+		"status":   "success",
+		"result":   result,
+		"duration": time.Since(start).String(),
+	})
+}
+
+// bindToolArguments 将请求体解析为参数对象，空请求体视为无参数
+// 以读到 io.EOF 判断请求体为空，而不是 ContentLength：分块传输的请求 ContentLength 为 -1；
+// 使用 UseNumber 保留整数精度
+func bindToolArguments(c *gin.Context) (map[string]interface{}, error) {
+	args := map[string]interface{}{}
+	if c.Request.Body == nil {
+		return args, nil
+	}
+
+	decoder := json.NewDecoder(c.Request.Body)
+	decoder.UseNumber()
+	if err := decoder.Decode(&args); err != nil {
+		if errors.Is(err, io.EOF) {
+			return map[string]interface{}{}, nil
+		}
+		return nil, fmt.Errorf("请求体必须是JSON对象: %v", err)
+	}
+	if args == nil {
+		args = map[string]interface{}{}
+	}
+	return args, nil
+}
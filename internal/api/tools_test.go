@@ -0,0 +1,114 @@
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// chunked 隐藏请求体长度，使 http.Client 以分块传输发送（ContentLength 为 -1）
+func chunked(body string) io.Reader {
+	return struct{ io.Reader }{strings.NewReader(body)}
+}
+
+func TestBindToolArguments(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/args", func(c *gin.Context) {
+		args, err := bindToolArguments(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "content_length": c.Request.ContentLength})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"args": args, "content_length": c.Request.ContentLength})
+	})
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	tests := []struct {
+		name       string
+		body       io.Reader
+		wantStatus int
+		wantArgs   string
+		wantLength int64
+	}{
+		{"空请求体", nil, http.StatusOK, `{}`, 0},
+		{"只有空白", strings.NewReader(" \n"), http.StatusOK, `{}`, 2},
+		{"JSON对象", strings.NewReader(`{"n":12345678901234567}`), http.StatusOK, `{"n":12345678901234567}`, 23},
+		{"分块传输的JSON对象", chunked(`{"text":"hi"}`), http.StatusOK, `{"text":"hi"}`, -1},
+		{"分块传输的空请求体", chunked(""), http.StatusOK, `{}`, -1},
+		{"分块传输的无效JSON", chunked(`{"text":`), http.StatusBadRequest, "", -1},
+		{"不是对象", strings.NewReader(`[1,2]`), http.StatusBadRequest, "", 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := http.Post(srv.URL+"/args", "application/json", tt.body)
+			if err != nil {
+				t.Fatalf("POST error = %v", err)
+			}
+			defer resp.Body.Close()
+
+			var body struct {
+				Args          json.RawMessage `json:"args"`
+				ContentLength int64           `json:"content_length"`
+			}
+			if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+				t.Fatalf("解析响应失败: %v", err)
+			}
+			if resp.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+			if tt.wantArgs != "" && string(body.Args) != tt.wantArgs {
+				t.Errorf("args = %s, want %s", body.Args, tt.wantArgs)
+			}
+			if body.ContentLength != tt.wantLength {
+				t.Errorf("ContentLength = %d, want %d", body.ContentLength, tt.wantLength)
+			}
+		})
+	}
+}
+
+func TestCreateConversationOptionalBody(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	h := NewHandlers(nil, nil, &AIConfig{}, &DatabaseConfig{}, nil)
+	r := gin.New()
+	r.POST("/conversations", h.CreateConversationHandler)
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	tests := []struct {
+		name       string
+		body       io.Reader
+		wantStatus int
+		wantTitle  string
+	}{
+		{"空请求体", nil, http.StatusCreated, ""},
+		{"JSON请求体", strings.NewReader(`{"title":"周报"}`), http.StatusCreated, "周报"},
+		{"分块传输的请求体", chunked(`{"title":"周报"}`), http.StatusCreated, "周报"},
+		{"无效JSON", chunked(`{"title":`), http.StatusBadRequest, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := http.Post(srv.URL+"/conversations", "application/json", tt.body)
+			if err != nil {
+				t.Fatalf("POST error = %v", err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+			var conv struct {
+				Title string `json:"title"`
+			}
+			json.NewDecoder(resp.Body).Decode(&conv)
+			if conv.Title != tt.wantTitle {
+				t.Errorf("title = %q, want %q", conv.Title, tt.wantTitle)
+			}
+		})
+	}
+}
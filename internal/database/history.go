@@ -0,0 +1,454 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// 历史记录表，由迁移 0002_create_history_tables 创建
+const (
+	ToolInvocationTable      = "mcp_tool_invocations"
+	ConversationTable        = "mcp_conversations"
+	ConversationMessageTable = "mcp_conversation_messages"
+)
+
+// HistoryMigrationVersion 创建历史记录表的迁移版本
+const HistoryMigrationVersion = 2
+
+// DefaultUserTable 迁移 0001_create_mcp_user 创建的用户表，配置其他表名时需自行建表
+const DefaultUserTable = "mcp_user"
+
+// HistorySchemaReady 历史记录表的迁移是否已执行
+func (c *MySQLClient) HistorySchemaReady(ctx context.Context) (bool, error) {
+	states, err := c.MigrationStatus(ctx)
+	if err != nil {
+		return false, err
+	}
+	for _, state := range states {
+		if state.Version == HistoryMigrationVersion {
+			return state.Applied, nil
+		}
+	}
+	return false, nil
+}
+
+// 历史查询默认和最大分页大小
+const (
+	defaultHistoryLimit = 50
+	maxHistoryLimit     = 500
+)
+
+// ToolInvocation 一次MCP工具调用记录
+type ToolInvocation struct {
+	ID             int64     `json:"id"`
+	Tool           string    `json:"tool"`
+	Arguments      string    `json:"arguments"` // JSON
+	Result         string    `json:"result"`    // JSON，失败且无结果时为空
+	Status         string    `json:"status"`    // success 或 error
+	ErrorClass     string    `json:"error_class,omitempty"`
+	ErrorMessage   string    `json:"error_message,omitempty"`
+	Provider       string    `json:"provider,omitempty"`
+	Model          string    `json:"model,omitempty"`
+	ConversationID string    `json:"conversation_id,omitempty"`
+	DurationMs     int64     `json:"duration_ms"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// ToolInvocationFilter 工具调用记录查询条件，零值字段不参与过滤
+type ToolInvocationFilter struct {
+	Tool           string
+	Status         string
+	ErrorClass     string
+	Provider       string
+	Model          string
+	ConversationID string
+	Since          time.Time
+	Until          time.Time
+	Limit          int
+	Offset         int
+}
+
+// ConversationRecord 会话记录
+type ConversationRecord struct {
+	ID           string     `json:"id"`
+	Title        string     `json:"title,omitempty"`
+	SystemPrompt string     `json:"system_prompt,omitempty"`
+	Provider     string     `json:"provider,omitempty"`
+	Model        string     `json:"model,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+	DeletedAt    *time.Time `json:"deleted_at,omitempty"`
+}
+
+// ConversationFilter 会话记录查询条件
+type ConversationFilter struct {
+	IncludeDeleted bool
+	Since          time.Time
+	Until          time.Time
+	Limit          int
+	Offset         int
+}
+
+// MessageRecord 会话消息记录
+type MessageRecord struct {
+	ID             int64     `json:"id"`
+	ConversationID string    `json:"conversation_id"`
+	Role           string    `json:"role"`
+	Content        string    `json:"content"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// MessageFilter 会话消息查询条件，Keyword 按内容模糊匹配
+type MessageFilter struct {
+	ConversationID string
+	Role           string
+	Keyword        string
+	Since          time.Time
+	Until          time.Time
+	Limit          int
+	Offset         int
+}
+
+// InsertToolInvocation 写入一条工具调用记录
+func (c *MySQLClient) InsertToolInvocation(ctx context.Context, inv *ToolInvocation) error {
+	query := "INSERT INTO `" + ToolInvocationTable + "` " +
+		"(tool, arguments, result, status, error_class, error_message, provider, model, conversation_id, duration_ms, created_at) " +
+		"VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"
+	res, err := c.db.ExecContext(ctx, query,
+		inv.Tool, inv.Arguments, inv.Result, inv.Status, inv.ErrorClass, inv.ErrorMessage,
+		inv.Provider, inv.Model, inv.ConversationID, inv.DurationMs, inv.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("写入工具调用记录失败: %v", err)
+	}
+	inv.ID, _ = res.LastInsertId()
+	return nil
+}
+
+// ListToolInvocations 按条件分页查询工具调用记录（按时间倒序），同时返回满足条件的总数
+func (c *MySQLClient) ListToolInvocations(ctx context.Context, filter ToolInvocationFilter) ([]ToolInvocation, int, error) {
+	var where conditions
+	where.eq("tool", filter.Tool)
+	where.eq("status", filter.Status)
+	where.eq("error_class", filter.ErrorClass)
+	where.eq("provider", filter.Provider)
+	where.eq("model", filter.Model)
+	where.eq("conversation_id", filter.ConversationID)
+	where.timeRange("created_at", filter.Since, filter.Until)
+
+	total, err := c.count(ctx, ToolInvocationTable, where)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	query := "SELECT id, tool, arguments, result, status, error_class, error_message, provider, model, conversation_id, duration_ms, created_at " +
+		"FROM `" + ToolInvocationTable + "`" + where.sql() + " ORDER BY id DESC LIMIT ? OFFSET ?"
+	rows, err := c.db.QueryContext(ctx, query, where.withPage(filter.Limit, filter.Offset)...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("查询工具调用记录失败: %v", err)
+	}
+	defer rows.Close()
+
+	invocations := []ToolInvocation{}
+	for rows.Next() {
+		inv, err := scanToolInvocation(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		invocations = append(invocations, *inv)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("遍历结果集失败: %v", err)
+	}
+	return invocations, total, nil
+}
+
+// GetToolInvocation 按ID查询工具调用记录，不存在时返回 sql.ErrNoRows
+func (c *MySQLClient) GetToolInvocation(ctx context.Context, id int64) (*ToolInvocation, error) {
+	query := "SELECT id, tool, arguments, result, status, error_class, error_message, provider, model, conversation_id, duration_ms, created_at " +
+		"FROM `" + ToolInvocationTable + "` WHERE id = ?"
+	return scanToolInvocation(c.db.QueryRowContext(ctx, query, id))
+}
+
+// InsertConversation 写入会话记录
+func (c *MySQLClient) InsertConversation(ctx context.Context, conv *ConversationRecord) error {
+	query := "INSERT INTO `" + ConversationTable + "` (id, title, system_prompt, provider, model, created_at, updated_at) " +
+		"VALUES (?, ?, ?, ?, ?, ?, ?)"
+	_, err := c.db.ExecContext(ctx, query,
+		conv.ID, conv.Title, conv.SystemPrompt, conv.Provider, conv.Model, conv.CreatedAt, conv.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("写入会话记录失败: %v", err)
+	}
+	return nil
+}
+
+// GetConversation 查询未删除的会话，不存在时返回 sql.ErrNoRows
+func (c *MySQLClient) GetConversation(ctx context.Context, id string) (*ConversationRecord, error) {
+	query := "SELECT id, title, system_prompt, provider, model, created_at, updated_at, deleted_at " +
+		"FROM `" + ConversationTable + "` WHERE id = ? AND deleted_at IS NULL"
+	return scanConversation(c.db.QueryRowContext(ctx, query, id))
+}
+
+// ListConversations 按条件分页查询会话记录（按最近活跃倒序），同时返回总数
+func (c *MySQLClient) ListConversations(ctx context.Context, filter ConversationFilter) ([]ConversationRecord, int, error) {
+	var where conditions
+	if !filter.IncludeDeleted {
+		where.add("deleted_at IS NULL")
+	}
+	where.timeRange("updated_at", filter.Since, filter.Until)
+
+	total, err := c.count(ctx, ConversationTable, where)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	query := "SELECT id, title, system_prompt, provider, model, created_at, updated_at, deleted_at " +
+		"FROM `" + ConversationTable + "`" + where.sql() + " ORDER BY updated_at DESC LIMIT ? OFFSET ?"
+	rows, err := c.db.QueryContext(ctx, query, where.withPage(filter.Limit, filter.Offset)...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("查询会话记录失败: %v", err)
+	}
+	defer rows.Close()
+
+	conversations := []ConversationRecord{}
+	for rows.Next() {
+		conv, err := scanConversation(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		conversations = append(conversations, *conv)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("遍历结果集失败: %v", err)
+	}
+	return conversations, total, nil
+}
+
+// DeleteConversation 软删除会话：保留消息供审计，会话不再可用
+func (c *MySQLClient) DeleteConversation(ctx context.Context, id string) error {
+	query := "UPDATE `" + ConversationTable + "` SET deleted_at = ? WHERE id = ? AND deleted_at IS NULL"
+	if _, err := c.db.ExecContext(ctx, query, time.Now(), id); err != nil {
+		return fmt.Errorf("删除会话记录失败: %v", err)
+	}
+	return nil
+}
+
+// InsertMessages 在一个事务中写入会话消息并更新会话活跃时间
+func (c *MySQLClient) InsertMessages(ctx context.Context, conversationID string, messages []MessageRecord) error {
+	tx, err := c.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("开启事务失败: %v", err)
+	}
+	defer tx.Rollback()
+
+	query := "INSERT INTO `" + ConversationMessageTable + "` (conversation_id, role, content, created_at) VALUES (?, ?, ?, ?)"
+	var updatedAt time.Time
+	for _, msg := range messages {
+		if _, err := tx.ExecContext(ctx, query, conversationID, msg.Role, msg.Content, msg.CreatedAt); err != nil {
+			return fmt.Errorf("写入会话消息失败: %v", err)
+		}
+		if msg.CreatedAt.After(updatedAt) {
+			updatedAt = msg.CreatedAt
+		}
+	}
+
+	update := "UPDATE `" + ConversationTable + "` SET updated_at = ? WHERE id = ?"
+	if _, err := tx.ExecContext(ctx, update, updatedAt, conversationID); err != nil {
+		return fmt.Errorf("更新会话记录失败: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("提交事务失败: %v", err)
+	}
+	return nil
+}
+
+// ListMessages 按条件分页查询会话消息（按写入顺序），同时返回总数
+func (c *MySQLClient) ListMessages(ctx context.Context, filter MessageFilter) ([]MessageRecord, int, error) {
+	var where conditions
+	where.eq("conversation_id", filter.ConversationID)
+	where.eq("role", filter.Role)
+	if filter.Keyword != "" {
+		where.add("content LIKE ?", "%"+escapeLike(filter.Keyword)+"%")
+	}
+	where.timeRange("created_at", filter.Since, filter.Until)
+
+	total, err := c.count(ctx, ConversationMessageTable, where)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	query := "SELECT id, conversation_id, role, content, created_at " +
+		"FROM `" + ConversationMessageTable + "`" + where.sql() + " ORDER BY id ASC LIMIT ? OFFSET ?"
+	rows, err := c.db.QueryContext(ctx, query, where.withPage(filter.Limit, filter.Offset)...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("查询会话消息失败: %v", err)
+	}
+	defer rows.Close()
+
+	messages := []MessageRecord{}
+	for rows.Next() {
+		var msg MessageRecord
+		var createdAt dbTime
+		if err := rows.Scan(&msg.ID, &msg.ConversationID, &msg.Role, &msg.Content, &createdAt); err != nil {
+			return nil, 0, fmt.Errorf("扫描行数据失败: %v", err)
+		}
+		msg.CreatedAt = createdAt.Time
+		messages = append(messages, msg)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("遍历结果集失败: %v", err)
+	}
+	return messages, total, nil
+}
+
+// count 统计满足条件的记录数
+func (c *MySQLClient) count(ctx context.Context, table string, where conditions) (int, error) {
+	var total int
+	query := "SELECT COUNT(*) FROM `" + table + "`" + where.sql()
+	if err := c.db.QueryRowContext(ctx, query, where.args...).Scan(&total); err != nil {
+		return 0, fmt.Errorf("统计%s表记录数失败: %v", table, err)
+	}
+	return total, nil
+}
+
+// rowScanner 兼容 *sql.Row 和 *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanToolInvocation 扫描一行工具调用记录
+func scanToolInvocation(row rowScanner) (*ToolInvocation, error) {
+	var inv ToolInvocation
+	var arguments, result, errorMessage sql.NullString
+	var createdAt dbTime
+	err := row.Scan(&inv.ID, &inv.Tool, &arguments, &result, &inv.Status, &inv.ErrorClass, &errorMessage,
+		&inv.Provider, &inv.Model, &inv.ConversationID, &inv.DurationMs, &createdAt)
+	if err == sql.ErrNoRows {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("扫描行数据失败: %v", err)
+	}
+	inv.Arguments = arguments.String
+	inv.Result = result.String
+	inv.ErrorMessage = errorMessage.String
+	inv.CreatedAt = createdAt.Time
+	return &inv, nil
+}
+
+// scanConversation 扫描一行会话记录
+func scanConversation(row rowScanner) (*ConversationRecord, error) {
+	var conv ConversationRecord
+	var systemPrompt sql.NullString
+	var createdAt, updatedAt, deletedAt dbTime
+	err := row.Scan(&conv.ID, &conv.Title, &systemPrompt, &conv.Provider, &conv.Model, &createdAt, &updatedAt, &deletedAt)
+	if err == sql.ErrNoRows {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("扫描行数据失败: %v", err)
+	}
+	conv.SystemPrompt = systemPrompt.String
+	conv.CreatedAt = createdAt.Time
+	conv.UpdatedAt = updatedAt.Time
+	if deletedAt.Valid {
+		t := deletedAt.Time
+		conv.DeletedAt = &t
+	}
+	return &conv, nil
+}
+
+// conditions 动态拼接 WHERE 条件
+type conditions struct {
+	clauses []string
+	args    []interface{}
+}
+
+// add 追加一个条件
+func (w *conditions) add(clause string, args ...interface{}) {
+	w.clauses = append(w.clauses, clause)
+	w.args = append(w.args, args...)
+}
+
+// eq 值非空时追加等值条件
+func (w *conditions) eq(column, value string) {
+	if value != "" {
+		w.add("`"+column+"` = ?", value)
+	}
+}
+
+// timeRange 追加时间区间条件 [since, until)
+func (w *conditions) timeRange(column string, since, until time.Time) {
+	if !since.IsZero() {
+		w.add("`"+column+"` >= ?", since)
+	}
+	if !until.IsZero() {
+		w.add("`"+column+"` < ?", until)
+	}
+}
+
+// sql 返回 WHERE 子句，无条件时为空
+func (w *conditions) sql() string {
+	if len(w.clauses) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(w.clauses, " AND ")
+}
+
+// withPage 返回追加了 LIMIT/OFFSET 参数的参数列表
+func (w *conditions) withPage(limit, offset int) []interface{} {
+	if limit <= 0 {
+		limit = defaultHistoryLimit
+	}
+	if limit > maxHistoryLimit {
+		limit = maxHistoryLimit
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	return append(append([]interface{}{}, w.args...), limit, offset)
+}
+
+// escapeLike 转义 LIKE 通配符
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// dbTime 兼容 parse_time 开启（time.Time）和关闭（[]uint8）两种驱动返回值的时间字段
+type dbTime struct {
+	Time  time.Time
+	Valid bool
+}
+
+// Scan 实现 sql.Scanner
+func (t *dbTime) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		t.Time, t.Valid = time.Time{}, false
+		return nil
+	case time.Time:
+		t.Time, t.Valid = v, true
+		return nil
+	case []byte:
+		return t.parse(string(v))
+	case string:
+		return t.parse(v)
+	default:
+		return fmt.Errorf("无法解析时间字段: %T", value)
+	}
+}
+
+// parse 解析 MySQL DATETIME 文本
+func (t *dbTime) parse(s string) error {
+	parsed, err := time.ParseInLocation("2006-01-02 15:04:05.999999", s, time.Local)
+	if err != nil {
+		return fmt.Errorf("解析时间字段失败: %v", err)
+	}
+	t.Time, t.Valid = parsed, true
+	return nil
+}
@@ -0,0 +1,416 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// migrationFiles 内嵌的迁移脚本，文件名格式为 NNNN_name.up.sql / NNNN_name.down.sql
+//
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// MigrationTable 记录已执行迁移版本的表
+const MigrationTable = "schema_migrations"
+
+// BaselineMigrationVersion 基线迁移版本，不允许回滚：
+// 0001_create_mcp_user 创建的 mcp_user 可能在引入迁移之前就已存在并保存业务数据
+const BaselineMigrationVersion = 1
+
+// 迁移锁：同一数据库上的多个实例通过 GET_LOCK 串行执行迁移
+const (
+	migrationLockName    = "mcp_ai_client_migrate"
+	migrationLockTimeout = 60 // 秒
+)
+
+// migrationFilePattern 匹配迁移文件名
+var migrationFilePattern = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// Migration 一个版本的迁移脚本
+// MySQL 的DDL会隐式提交，脚本中途失败时已执行的语句不会回滚，因此脚本应可重复执行（如 CREATE TABLE IF NOT EXISTS）
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationState 迁移版本的执行状态
+type MigrationState struct {
+	Version   int64      `json:"version"`
+	Name      string     `json:"name"`
+	Applied   bool       `json:"applied"`
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
+	Missing   bool       `json:"missing,omitempty"` // 数据库中已执行但当前程序不包含该版本
+}
+
+// Migrations 返回内嵌的全部迁移，按版本升序
+func Migrations() ([]Migration, error) {
+	return loadMigrations(migrationFiles, "migrations")
+}
+
+// loadMigrations 从目录读取迁移脚本，每个版本必须同时有 up 和 down 脚本
+func loadMigrations(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("读取迁移目录失败: %v", err)
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		match := migrationFilePattern.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("迁移文件名不合法: %s", entry.Name())
+		}
+		version, _ := strconv.ParseInt(match[1], 10, 64)
+		data, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("读取迁移文件失败: %v", err)
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		} else if m.Name != match[2] {
+			return nil, fmt.Errorf("迁移版本 %d 名称冲突: %s / %s", version, m.Name, match[2])
+		}
+		if match[3] == "up" {
+			m.Up = string(data)
+		} else {
+			m.Down = string(data)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if strings.TrimSpace(m.Up) == "" || strings.TrimSpace(m.Down) == "" {
+			return nil, fmt.Errorf("迁移版本 %d 缺少 up 或 down 脚本", m.Version)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+// Migrate 执行全部未执行的迁移，返回本次执行的迁移
+func (c *MySQLClient) Migrate(ctx context.Context) ([]Migration, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+
+	var applied []Migration
+	err = c.withMigrationLock(ctx, func(conn *sql.Conn) error {
+		done, err := appliedMigrations(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, m := range migrations {
+			if _, ok := done[m.Version]; ok {
+				continue
+			}
+			if err := execScript(ctx, conn, m.Up); err != nil {
+				return fmt.Errorf("执行迁移 %04d_%s 失败: %w", m.Version, m.Name, err)
+			}
+			if _, err := conn.ExecContext(ctx,
+				"INSERT INTO `"+MigrationTable+"` (version, name, applied_at) VALUES (?, ?, ?)",
+				m.Version, m.Name, time.Now(),
+			); err != nil {
+				return fmt.Errorf("记录迁移版本 %d 失败: %v", m.Version, err)
+			}
+			c.logger.InfoContext(ctx, "已执行数据库迁移", "version", m.Version, "name", m.Name)
+			applied = append(applied, m)
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// MigrateDown 按版本从新到旧回滚 steps 个已执行的迁移，返回本次回滚的迁移
+// 回滚范围包含基线版本时不执行任何回滚并返回错误
+func (c *MySQLClient) MigrateDown(ctx context.Context, steps int) ([]Migration, error) {
+	if steps <= 0 {
+		return nil, fmt.Errorf("回滚步数必须大于0")
+	}
+	migrations, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+	known := make(map[int64]Migration, len(migrations))
+	for _, m := range migrations {
+		known[m.Version] = m
+	}
+
+	var reverted []Migration
+	err = c.withMigrationLock(ctx, func(conn *sql.Conn) error {
+		done, err := appliedMigrations(ctx, conn)
+		if err != nil {
+			return err
+		}
+		versions := make([]int64, 0, len(done))
+		for version := range done {
+			versions = append(versions, version)
+		}
+		sort.Slice(versions, func(i, j int) bool { return versions[i] > versions[j] })
+		if len(versions) > steps {
+			versions = versions[:steps]
+		}
+		for _, version := range versions {
+			if version <= BaselineMigrationVersion {
+				return fmt.Errorf("不能回滚到版本 %d 以下: 基线迁移 %04d 创建的 %s 表可能保存业务数据，确需删除请手动执行 DROP TABLE",
+					BaselineMigrationVersion, BaselineMigrationVersion, DefaultUserTable)
+			}
+		}
+
+		for _, version := range versions {
+			m, ok := known[version]
+			if !ok {
+				return fmt.Errorf("迁移版本 %d 已执行但程序中没有对应的脚本，无法回滚", version)
+			}
+			if err := execScript(ctx, conn, m.Down); err != nil {
+				return fmt.Errorf("回滚迁移 %04d_%s 失败: %w", m.Version, m.Name, err)
+			}
+			if _, err := conn.ExecContext(ctx, "DELETE FROM `"+MigrationTable+"` WHERE version = ?", m.Version); err != nil {
+				return fmt.Errorf("删除迁移版本 %d 记录失败: %v", m.Version, err)
+			}
+			c.logger.InfoContext(ctx, "已回滚数据库迁移", "version", m.Version, "name", m.Name)
+			reverted = append(reverted, m)
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// MigrationStatus 返回每个迁移版本的执行状态，按版本升序
+// 只读：迁移版本表不存在时视为没有执行过任何迁移，不会创建该表
+func (c *MySQLClient) MigrationStatus(ctx context.Context) ([]MigrationState, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := c.db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("获取数据库连接失败: %v", err)
+	}
+	defer conn.Close()
+
+	done := make(map[int64]appliedMigration)
+	exists, err := migrationTableExists(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		if done, err = appliedMigrations(ctx, conn); err != nil {
+			return nil, err
+		}
+	}
+
+	states := make([]MigrationState, 0, len(migrations))
+	for _, m := range migrations {
+		state := MigrationState{Version: m.Version, Name: m.Name}
+		if record, ok := done[m.Version]; ok {
+			state.Applied = true
+			state.AppliedAt = &record.appliedAt
+			delete(done, m.Version)
+		}
+		states = append(states, state)
+	}
+	for version, record := range done {
+		appliedAt := record.appliedAt
+		states = append(states, MigrationState{
+			Version:   version,
+			Name:      record.name,
+			Applied:   true,
+			AppliedAt: &appliedAt,
+			Missing:   true,
+		})
+	}
+	sort.Slice(states, func(i, j int) bool {
+		return states[i].Version < states[j].Version
+	})
+	return states, nil
+}
+
+// withMigrationLock 在持有迁移锁的专用连接上执行 fn
+// GET_LOCK 绑定到数据库会话，因此加锁、迁移、解锁必须使用同一个连接
+func (c *MySQLClient) withMigrationLock(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := c.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("获取数据库连接失败: %v", err)
+	}
+	defer conn.Close()
+
+	var locked sql.NullInt64
+	if err := conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, ?)", migrationLockName, migrationLockTimeout).Scan(&locked); err != nil {
+		return fmt.Errorf("获取迁移锁失败: %v", err)
+	}
+	if !locked.Valid || locked.Int64 != 1 {
+		return fmt.Errorf("获取迁移锁超时: 其他实例正在执行迁移")
+	}
+	defer func() {
+		// 请求方取消时也要释放锁
+		if _, err := conn.ExecContext(context.WithoutCancel(ctx), "SELECT RELEASE_LOCK(?)", migrationLockName); err != nil {
+			c.logger.WarnContext(ctx, "释放迁移锁失败", "error", err)
+		}
+	}()
+
+	if err := ensureMigrationTable(ctx, conn); err != nil {
+		return err
+	}
+	return fn(conn)
+}
+
+// ensureMigrationTable 创建迁移版本表（已存在时跳过）
+func ensureMigrationTable(ctx context.Context, conn *sql.Conn) error {
+	_, err := conn.ExecContext(ctx, "CREATE TABLE IF NOT EXISTS `"+MigrationTable+"` ("+
+		"`version` BIGINT NOT NULL,"+
+		"`name` VARCHAR(255) NOT NULL,"+
+		"`applied_at` DATETIME(3) NOT NULL,"+
+		"PRIMARY KEY (`version`)"+
+		") ENGINE=InnoDB DEFAULT CHARSET=utf8mb4")
+	if err != nil {
+		return fmt.Errorf("创建迁移版本表失败: %v", err)
+	}
+	return nil
+}
+
+// migrationTableExists 迁移版本表是否存在于当前数据库
+func migrationTableExists(ctx context.Context, conn *sql.Conn) (bool, error) {
+	var n int
+	err := conn.QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = DATABASE() AND table_name = ?",
+		MigrationTable,
+	).Scan(&n)
+	if err != nil {
+		return false, fmt.Errorf("查询迁移版本表失败: %v", err)
+	}
+	return n > 0, nil
+}
+
+// appliedMigration 已执行迁移的记录
+type appliedMigration struct {
+	name      string
+	appliedAt time.Time
+}
+
+// appliedMigrations 读取已执行的迁移版本
+func appliedMigrations(ctx context.Context, conn *sql.Conn) (map[int64]appliedMigration, error) {
+	rows, err := conn.QueryContext(ctx, "SELECT version, name, applied_at FROM `"+MigrationTable+"`")
+	if err != nil {
+		return nil, fmt.Errorf("查询迁移版本失败: %v", err)
+	}
+	defer rows.Close()
+
+	done := make(map[int64]appliedMigration)
+	for rows.Next() {
+		var version int64
+		var record appliedMigration
+		var appliedAt dbTime
+		if err := rows.Scan(&version, &record.name, &appliedAt); err != nil {
+			return nil, fmt.Errorf("扫描迁移版本失败: %v", err)
+		}
+		record.appliedAt = appliedAt.Time
+		done[version] = record
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("遍历迁移版本失败: %v", err)
+	}
+	return done, nil
+}
+
+// execScript 逐条执行迁移脚本中的语句
+func execScript(ctx context.Context, conn *sql.Conn, script string) error {
+	for _, stmt := range splitStatements(script) {
+		if _, err := conn.ExecContext(ctx, stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// splitStatements 将脚本拆分为单条语句
+// DSN 未开启 multiStatements，需要逐条执行；按分号拆分，但忽略引号（'、"、`）和 /* */ 注释中的分号，
+// -- 和 # 行注释被移除（按 MySQL 规则，-- 后须跟空白才是注释）
+func splitStatements(script string) []string {
+	var statements []string
+	var current strings.Builder
+	flush := func() {
+		if stmt := strings.TrimSpace(current.String()); stmt != "" {
+			statements = append(statements, stmt)
+		}
+		current.Reset()
+	}
+
+	for i := 0; i < len(script); i++ {
+		ch := script[i]
+		switch {
+		case ch == '\'', ch == '"', ch == '`':
+			end := quotedEnd(script, i)
+			current.WriteString(script[i:end])
+			i = end - 1
+		case ch == '#' || (ch == '-' && strings.HasPrefix(script[i:], "--") && (i+2 == len(script) || isSpace(script[i+2]))):
+			// 行注释保留换行，避免前后两行拼接在一起
+			for i < len(script) && script[i] != '\n' {
+				i++
+			}
+			current.WriteByte('\n')
+		case ch == '/' && strings.HasPrefix(script[i:], "/*"):
+			end := strings.Index(script[i+2:], "*/")
+			if end < 0 {
+				end = len(script)
+			} else {
+				end += i + 4
+			}
+			current.WriteString(script[i:end])
+			i = end - 1
+		case ch == ';':
+			flush()
+		default:
+			current.WriteByte(ch)
+		}
+	}
+	flush()
+	return statements
+}
+
+// quotedEnd 返回从 start 处引号开始的字符串或标识符结束后的位置，未闭合时返回脚本长度
+// 引号重复两次表示引号本身；单双引号字符串中反斜杠转义下一个字符
+func quotedEnd(script string, start int) int {
+	quote := script[start]
+	for i := start + 1; i < len(script); i++ {
+		switch script[i] {
+		case '\\':
+			if quote != '`' {
+				i++
+			}
+		case quote:
+			if i+1 < len(script) && script[i+1] == quote {
+				i++
+				continue
+			}
+			return i + 1
+		}
+	}
+	return len(script)
+}
+
+// isSpace 是否为 MySQL 注释规则中的空白字符
+func isSpace(ch byte) bool {
+	return ch == ' ' || ch == '\t' || ch == '\n' || ch == '\r'
+}
@@ -0,0 +1,117 @@
+package database
+
+import (
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+func TestSplitStatements(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+		want   []string
+	}{
+		{
+			name:   "多条语句",
+			script: "CREATE TABLE a (id INT);\nCREATE TABLE b (id INT);\n",
+			want:   []string{"CREATE TABLE a (id INT)", "CREATE TABLE b (id INT)"},
+		},
+		{
+			name:   "最后一条没有分号",
+			script: "DROP TABLE a;\nDROP TABLE b",
+			want:   []string{"DROP TABLE a", "DROP TABLE b"},
+		},
+		{
+			name:   "同一行多条语句",
+			script: "DROP TABLE a; DROP TABLE b;",
+			want:   []string{"DROP TABLE a", "DROP TABLE b"},
+		},
+		{
+			name:   "空语句",
+			script: ";;\n  ;\n",
+		},
+		{
+			name:   "只有注释",
+			script: "-- 回滚时保留该表\n# DROP TABLE a;\n",
+		},
+		{
+			name:   "整行注释中的分号",
+			script: "-- 第一步; 建表\nCREATE TABLE a (id INT);\n",
+			want:   []string{"CREATE TABLE a (id INT)"},
+		},
+		{
+			name:   "行尾注释",
+			script: "CREATE TABLE a (id INT); -- 用户表\nCREATE TABLE b (id INT); # 日志表\n",
+			want:   []string{"CREATE TABLE a (id INT)", "CREATE TABLE b (id INT)"},
+		},
+		{
+			name:   "语句中间的行注释",
+			script: "CREATE TABLE a (\n  id INT, -- 主键; 自增\n  name TEXT\n);",
+			want:   []string{"CREATE TABLE a (\n  id INT, \n  name TEXT\n)"},
+		},
+		{
+			name:   "块注释中的分号",
+			script: "/* 版本1; 初始化 */ CREATE TABLE a (id INT);",
+			want:   []string{"/* 版本1; 初始化 */ CREATE TABLE a (id INT)"},
+		},
+		{
+			name:   "单引号中的分号",
+			script: "INSERT INTO a VALUES ('x;y');\nINSERT INTO a VALUES ('z');",
+			want:   []string{"INSERT INTO a VALUES ('x;y')", "INSERT INTO a VALUES ('z')"},
+		},
+		{
+			name:   "跨行字符串中行尾的分号",
+			script: "INSERT INTO a VALUES ('第一行;\n第二行');",
+			want:   []string{"INSERT INTO a VALUES ('第一行;\n第二行')"},
+		},
+		{
+			name:   "双引号和反引号中的分号",
+			script: "CREATE TABLE `a;b` (c TEXT COMMENT \"x;y\");",
+			want:   []string{"CREATE TABLE `a;b` (c TEXT COMMENT \"x;y\")"},
+		},
+		{
+			name:   "转义的引号",
+			script: `INSERT INTO a VALUES ('it\'s;', 'it''s;');`,
+			want:   []string{`INSERT INTO a VALUES ('it\'s;', 'it''s;')`},
+		},
+		{
+			name:   "引号中的注释标记",
+			script: "INSERT INTO a VALUES ('-- x; # y /* z');",
+			want:   []string{"INSERT INTO a VALUES ('-- x; # y /* z')"},
+		},
+		{
+			name:   "减号后没有空白不是注释",
+			script: "SELECT 1--1;",
+			want:   []string{"SELECT 1--1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := splitStatements(tt.script); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("splitStatements() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadMigrations(t *testing.T) {
+	migrations, err := Migrations()
+	if err != nil {
+		t.Fatalf("Migrations() error = %v", err)
+	}
+	if len(migrations) == 0 || migrations[0].Version != BaselineMigrationVersion {
+		t.Fatalf("第一个迁移应为基线版本 %d: %+v", BaselineMigrationVersion, migrations)
+	}
+	for _, m := range migrations {
+		if len(splitStatements(m.Up)) == 0 {
+			t.Errorf("迁移 %04d_%s 的 up 脚本没有语句", m.Version, m.Name)
+		}
+	}
+
+	if _, err := loadMigrations(fstest.MapFS{
+		"m/0001_a.up.sql": {Data: []byte("SELECT 1;")},
+	}, "m"); err == nil {
+		t.Error("缺少 down 脚本时应返回错误")
+	}
+}
@@ -0,0 +1,2 @@
+-- 基线版本不允许回滚（见 database.BaselineMigrationVersion），migrate down 到达此版本时直接报错，本脚本不会被执行。
+-- mcp_user 可能在引入迁移之前就已存在并保存业务数据；确认需要删除时手动执行: DROP TABLE IF EXISTS `mcp_user`;
@@ -0,0 +1,12 @@
+-- 传统查询使用的默认用户表（已存在时保留原表）
+-- 表名固定为 mcp_user，不跟随配置 database.tables.user_table；配置其他表名时需自行建表
+CREATE TABLE IF NOT EXISTS `mcp_user` (
+  `id` INT NOT NULL AUTO_INCREMENT,
+  `name` VARCHAR(64) NOT NULL,
+  `email` VARCHAR(128) NOT NULL DEFAULT '',
+  `department` VARCHAR(64) NOT NULL DEFAULT '',
+  `age` INT NOT NULL DEFAULT 0,
+  `salary` DECIMAL(12, 2) NOT NULL DEFAULT 0,
+  PRIMARY KEY (`id`),
+  KEY `idx_department` (`department`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS `mcp_conversation_messages`;
+DROP TABLE IF EXISTS `mcp_conversations`;
+DROP TABLE IF EXISTS `mcp_tool_invocations`;
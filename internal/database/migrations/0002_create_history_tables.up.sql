@@ -0,0 +1,44 @@
+-- 工具调用记录
+CREATE TABLE IF NOT EXISTS `mcp_tool_invocations` (
+  `id` BIGINT NOT NULL AUTO_INCREMENT,
+  `tool` VARCHAR(128) NOT NULL,
+  `arguments` LONGTEXT NULL,
+  `result` LONGTEXT NULL,
+  `status` VARCHAR(16) NOT NULL,
+  `error_class` VARCHAR(32) NOT NULL DEFAULT '',
+  `error_message` TEXT NULL,
+  `provider` VARCHAR(64) NOT NULL DEFAULT '',
+  `model` VARCHAR(128) NOT NULL DEFAULT '',
+  `conversation_id` VARCHAR(64) NOT NULL DEFAULT '',
+  `duration_ms` BIGINT NOT NULL DEFAULT 0,
+  `created_at` DATETIME(3) NOT NULL,
+  PRIMARY KEY (`id`),
+  KEY `idx_tool_created` (`tool`, `created_at`),
+  KEY `idx_created` (`created_at`),
+  KEY `idx_conversation` (`conversation_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- 会话，删除为软删除
+CREATE TABLE IF NOT EXISTS `mcp_conversations` (
+  `id` VARCHAR(64) NOT NULL,
+  `title` VARCHAR(255) NOT NULL DEFAULT '',
+  `system_prompt` TEXT NULL,
+  `provider` VARCHAR(64) NOT NULL DEFAULT '',
+  `model` VARCHAR(128) NOT NULL DEFAULT '',
+  `created_at` DATETIME(3) NOT NULL,
+  `updated_at` DATETIME(3) NOT NULL,
+  `deleted_at` DATETIME(3) NULL,
+  PRIMARY KEY (`id`),
+  KEY `idx_updated` (`updated_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- 会话消息
+CREATE TABLE IF NOT EXISTS `mcp_conversation_messages` (
+  `id` BIGINT NOT NULL AUTO_INCREMENT,
+  `conversation_id` VARCHAR(64) NOT NULL,
+  `role` VARCHAR(16) NOT NULL,
+  `content` LONGTEXT NOT NULL,
+  `created_at` DATETIME(3) NOT NULL,
+  PRIMARY KEY (`id`),
+  KEY `idx_conversation` (`conversation_id`, `id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
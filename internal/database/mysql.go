@@ -1,12 +1,16 @@
 package database
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
-	"log"
+	"log/slog"
+	"mcp-ai-client/internal/logging"
 	"time"
 
+	"github.com/XSAM/otelsql"
 	_ "github.com/go-sql-driver/mysql"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
 )
 
 // MySQLConfig MySQL配置
@@ -23,11 +27,13 @@ type MySQLConfig struct {
 
 // MySQLClient MySQL客户端
 type MySQLClient struct {
-	db *sql.DB
+	db     *sql.DB
+	logger *slog.Logger
 }
 
-// NewMySQLClient 创建MySQL客户端
-func NewMySQLClient(config *MySQLConfig) (*MySQLClient, error) {
+// NewMySQLClient 创建MySQL客户端，logger 为 nil 时使用 slog.Default()
+func NewMySQLClient(config *MySQLConfig, logger *slog.Logger) (*MySQLClient, error) {
+	logger = logging.OrDefault(logger)
 	dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?charset=%s&parseTime=%v&loc=%s",
 		config.Username,
 		config.Password,
@@ -39,7 +45,14 @@ func NewMySQLClient(config *MySQLConfig) (*MySQLClient, error) {
 		config.Loc,
 	)
 
-	db, err := sql.Open("mysql", dsn)
+	// 每条SQL创建一个span（语句文本记录在 db.statement，不含参数值）
+	db, err := otelsql.Open("mysql", dsn,
+		otelsql.WithAttributes(semconv.DBSystemMySQL, semconv.DBNamespace(config.Database)),
+		otelsql.WithSpanOptions(otelsql.SpanOptions{
+			OmitConnResetSession: true,
+			OmitRows:             true,
+		}),
+	)
 	if err != nil {
 		return nil, fmt.Errorf("连接MySQL失败: %v", err)
 	}
@@ -54,8 +67,18 @@ func NewMySQLClient(config *MySQLConfig) (*MySQLClient, error) {
 		return nil, fmt.Errorf("MySQL连接测试失败: %v", err)
 	}
 
-	log.Println("MySQL连接成功")
-	return &MySQLClient{db: db}, nil
+	logger.Info("MySQL连接成功", "host", config.Host, "port", config.Port, "database", config.Database)
+	return &MySQLClient{db: db, logger: logger}, nil
+}
+
+// Ping 检查数据库连接是否可用
+func (c *MySQLClient) Ping(ctx context.Context) error {
+	return c.db.PingContext(ctx)
+}
+
+// Stats 返回连接池统计
+func (c *MySQLClient) Stats() sql.DBStats {
+	return c.db.Stats()
 }
 
 // Close 关闭数据库连接
@@ -67,12 +90,12 @@ func (c *MySQLClient) Close() error {
 }
 
 // QueryUser 查询指定用户表
-func (c *MySQLClient) QueryUser(tableName string) ([]map[string]interface{}, error) {
+func (c *MySQLClient) QueryUser(ctx context.Context, tableName string) ([]map[string]interface{}, error) {
 	if tableName == "" {
 		tableName = "mcp_user" // 默认表名
 	}
 	query := fmt.Sprintf("SELECT * FROM `%s` LIMIT 100", tableName)
-	rows, err := c.db.Query(query)
+	rows, err := c.db.QueryContext(ctx, query)
 	if err != nil {
 		return nil, fmt.Errorf("查询%s表失败: %v", tableName, err)
 	}
@@ -119,17 +142,17 @@ func (c *MySQLClient) QueryUser(tableName string) ([]map[string]interface{}, err
 }
 
 // QueryUserByID 根据ID查询指定用户表
-func (c *MySQLClient) QueryUserByID(id int, tableName string) (map[string]interface{}, error) {
+func (c *MySQLClient) QueryUserByID(ctx context.Context, id int, tableName string) (map[string]interface{}, error) {
 	if tableName == "" {
 		tableName = "mcp_user" // 默认表名
 	}
 	query := fmt.Sprintf("SELECT * FROM `%s` WHERE id = ?", tableName)
-	row := c.db.QueryRow(query, id)
+	row := c.db.QueryRowContext(ctx, query, id)
 
 	// 对于单行查询，我们需要知道列的结构
 	// 这里我们使用一个通用的查询来获取列信息
 	columnsQuery := fmt.Sprintf("SELECT * FROM `%s` LIMIT 1", tableName)
-	columnsRow, err := c.db.Query(columnsQuery)
+	columnsRow, err := c.db.QueryContext(ctx, columnsQuery)
 	if err != nil {
 		return nil, fmt.Errorf("获取列信息失败: %v", err)
 	}
@@ -167,13 +190,13 @@ func (c *MySQLClient) QueryUserByID(id int, tableName string) (map[string]interf
 }
 
 // GetUserCount 获取指定用户表记录数
-func (c *MySQLClient) GetUserCount(tableName string) (int, error) {
+func (c *MySQLClient) GetUserCount(ctx context.Context, tableName string) (int, error) {
 	if tableName == "" {
 		tableName = "mcp_user" // 默认表名
 	}
 	query := fmt.Sprintf("SELECT COUNT(*) FROM `%s`", tableName)
 	var count int
-	err := c.db.QueryRow(query).Scan(&count)
+	err := c.db.QueryRowContext(ctx, query).Scan(&count)
 	if err != nil {
 		return 0, fmt.Errorf("获取%s表记录数失败: %v", tableName, err)
 	}
@@ -181,12 +204,12 @@ func (c *MySQLClient) GetUserCount(tableName string) (int, error) {
 }
 
 // GetUserSchema 获取指定用户表结构
-func (c *MySQLClient) GetUserSchema(tableName string) ([]map[string]interface{}, error) {
+func (c *MySQLClient) GetUserSchema(ctx context.Context, tableName string) ([]map[string]interface{}, error) {
 	if tableName == "" {
 		tableName = "mcp_user" // 默认表名
 	}
 	query := fmt.Sprintf("DESCRIBE `%s`", tableName)
-	rows, err := c.db.Query(query)
+	rows, err := c.db.QueryContext(ctx, query)
 	if err != nil {
 		return nil, fmt.Errorf("获取%s表结构失败: %v", tableName, err)
 	}
@@ -0,0 +1,164 @@
+package logging
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// 输出格式
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// DefaultMaxPayload 单个字段值默认最多输出的字节数
+const DefaultMaxPayload = 2048
+
+// Config 日志配置
+type Config struct {
+	Level      string `yaml:"level"`       // debug、info（默认）、warn 或 error
+	Format     string `yaml:"format"`      // text（默认）或 json
+	MaxPayload int    `yaml:"max_payload"` // 单个字段值最多输出的字节数，超出部分截断，默认2048
+}
+
+// WithDefaults 填充可省略的配置
+func (c Config) WithDefaults() Config {
+	if c.Level == "" {
+		c.Level = "info"
+	}
+	if c.Format == "" {
+		c.Format = FormatText
+	}
+	if c.MaxPayload == 0 {
+		c.MaxPayload = DefaultMaxPayload
+	}
+	return c
+}
+
+// ParseLevel 解析日志级别名称
+func ParseLevel(name string) (slog.Level, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(name)); err != nil {
+		return 0, fmt.Errorf("无效的日志级别 %q，可选 debug、info、warn、error", name)
+	}
+	return level, nil
+}
+
+// New 按配置创建写入 w 的 logger
+// 所有字段按键名脱敏、超长值截断；通过 *Context 方法记录时附带请求ID和 trace_id
+// 返回的 LevelVar 可在运行中调整级别
+func New(w io.Writer, config Config) (*slog.Logger, *slog.LevelVar, error) {
+	config = config.WithDefaults()
+	level, err := ParseLevel(config.Level)
+	if err != nil {
+		return nil, nil, err
+	}
+	levelVar := &slog.LevelVar{}
+	levelVar.Set(level)
+
+	opts := &slog.HandlerOptions{
+		Level:       levelVar,
+		ReplaceAttr: replaceAttr(config.MaxPayload),
+	}
+	var handler slog.Handler
+	switch strings.ToLower(config.Format) {
+	case FormatText:
+		handler = slog.NewTextHandler(w, opts)
+	case FormatJSON:
+		handler = slog.NewJSONHandler(w, opts)
+	default:
+		return nil, nil, fmt.Errorf("无效的日志格式 %q，可选 text、json", config.Format)
+	}
+	return slog.New(&contextHandler{Handler: handler}), levelVar, nil
+}
+
+// OrDefault logger 为 nil 时返回 slog.Default()
+func OrDefault(logger *slog.Logger) *slog.Logger {
+	if logger == nil {
+		return slog.Default()
+	}
+	return logger
+}
+
+// replaceAttr 脱敏敏感字段并截断超长值，不处理时间、级别、消息等内置字段
+// 时长统一输出为 1.5s 形式（JSON 格式默认输出纳秒整数）
+func replaceAttr(maxPayload int) func(groups []string, a slog.Attr) slog.Attr {
+	return func(groups []string, a slog.Attr) slog.Attr {
+		if len(groups) == 0 {
+			switch a.Key {
+			case slog.TimeKey, slog.LevelKey, slog.MessageKey, slog.SourceKey:
+				return a
+			}
+		}
+		if IsSensitive(a.Key) {
+			return slog.String(a.Key, Redacted)
+		}
+
+		switch a.Value.Kind() {
+		case slog.KindDuration:
+			return slog.String(a.Key, a.Value.Duration().String())
+		case slog.KindString:
+			return slog.String(a.Key, Truncate(a.Value.String(), maxPayload))
+		case slog.KindAny:
+			switch v := a.Value.Any().(type) {
+			case error:
+				return slog.String(a.Key, Truncate(v.Error(), maxPayload))
+			case map[string]interface{}, []interface{}:
+				if data, err := json.Marshal(Redact(v)); err == nil {
+					return slog.String(a.Key, Truncate(string(data), maxPayload))
+				}
+			}
+		}
+		return a
+	}
+}
+
+// ===== 请求上下文 =====
+
+// requestIDKey 请求ID在 context 中的键
+type requestIDKey struct{}
+
+// WithRequestID 在 context 中记录请求ID，之后以该 context 记录的日志都带 request_id 字段
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID 返回 context 中的请求ID，没有时返回空字符串
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// contextHandler 从 context 中取出请求ID和当前span的 trace_id 附加到日志记录
+type contextHandler struct {
+	slog.Handler
+}
+
+// Handle 实现 slog.Handler
+func (h *contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if ctx != nil {
+		if id := RequestID(ctx); id != "" {
+			r.AddAttrs(slog.String("request_id", id))
+		}
+		if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+			r.AddAttrs(slog.String("trace_id", sc.TraceID().String()))
+		}
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+// WithAttrs 实现 slog.Handler
+func (h *contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+// WithGroup 实现 slog.Handler
+func (h *contextHandler) WithGroup(name string) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithGroup(name)}
+}
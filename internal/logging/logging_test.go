@@ -0,0 +1,135 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// logRecord 以 JSON 格式记录一条日志并解析输出
+func logRecord(t *testing.T, maxPayload int, ctx context.Context, args ...interface{}) map[string]interface{} {
+	t.Helper()
+	var buf bytes.Buffer
+	logger, _, err := New(&buf, Config{Format: FormatJSON, MaxPayload: maxPayload})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	logger.InfoContext(ctx, "测试", args...)
+
+	var record map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("解析日志输出失败: %v\n%s", err, buf.String())
+	}
+	return record
+}
+
+func TestHandlerRedactsSensitiveFields(t *testing.T) {
+	tests := []struct {
+		name string
+		key  string
+		val  interface{}
+		want string
+	}{
+		{"auth_info", "auth_info", "Bearer abc", Redacted},
+		{"password", "password", "s3cret", Redacted},
+		{"大小写不敏感", "Password", "s3cret", Redacted},
+		{"password后缀", "db_password", "s3cret", Redacted},
+		{"token后缀", "session_token", "t", Redacted},
+		{"DSN", "dsn", "root:s3cret@tcp(127.0.0.1:3306)/test", Redacted},
+		{"敏感字段为对象", "auth_info", map[string]interface{}{"user": "u"}, Redacted},
+		{
+			"嵌套对象",
+			"args",
+			map[string]interface{}{"query": "select 1", "db": map[string]interface{}{"dsn": "root:pw@/x", "password": "pw"}},
+			`{"db":{"dsn":"[REDACTED]","password":"[REDACTED]"},"query":"select 1"}`,
+		},
+		{
+			"数组中的对象",
+			"args",
+			[]interface{}{map[string]interface{}{"api_key": "k", "name": "a"}, "plain"},
+			`[{"api_key":"[REDACTED]","name":"a"},"plain"]`,
+		},
+		{
+			"原始报文",
+			"payload",
+			Payload([]byte(`{"params":{"arguments":{"auth_info":"x","text":"hi"}}}`)),
+			`{"params":{"arguments":{"auth_info":"[REDACTED]","text":"hi"}}}`,
+		},
+		{"非JSON报文原样输出", "payload", Payload([]byte("not json")), "not json"},
+		{"普通字段", "tool", "echo", "echo"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			record := logRecord(t, 0, context.Background(), tt.key, tt.val)
+			if got := record[tt.key]; got != tt.want {
+				t.Errorf("%s = %v, want %v", tt.key, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandlerTruncatesLongValues(t *testing.T) {
+	long := strings.Repeat("a", 100)
+	tests := []struct {
+		name string
+		val  interface{}
+	}{
+		{"字符串", long},
+		{"错误", errors.New(long)},
+		{"对象", map[string]interface{}{"text": long}},
+		{"报文", Payload([]byte(`{"text":"` + long + `"}`))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			record := logRecord(t, 32, context.Background(), "v", tt.val)
+			got, _ := record["v"].(string)
+			if !strings.Contains(got, "已截断") || len(got) > 32+len("...(已截断，共999字节)") {
+				t.Errorf("v = %q, want 截断到32字节", got)
+			}
+		})
+	}
+
+	record := logRecord(t, 32, context.Background(), "short", "ok")
+	if record["short"] != "ok" {
+		t.Errorf("short = %v, want ok", record["short"])
+	}
+	if record["msg"] != "测试" {
+		t.Errorf("msg = %v, 内置字段不应被处理", record["msg"])
+	}
+}
+
+func TestHandlerAddsRequestID(t *testing.T) {
+	record := logRecord(t, 0, WithRequestID(context.Background(), "req-1"))
+	if record["request_id"] != "req-1" {
+		t.Errorf("request_id = %v, want req-1", record["request_id"])
+	}
+
+	record = logRecord(t, 0, context.Background())
+	if _, ok := record["request_id"]; ok {
+		t.Errorf("没有请求ID时不应输出 request_id: %v", record)
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		name string
+		s    string
+		max  int
+		want string
+	}{
+		{"不超过上限", "hello", 5, "hello"},
+		{"不限制", "hello", 0, "hello"},
+		{"超过上限", "hello world", 5, "hello...(已截断，共11字节)"},
+		{"不切断多字节字符", "你好世界", 4, "你...(已截断，共12字节)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Truncate(tt.s, tt.max); got != tt.want {
+				t.Errorf("Truncate(%q, %d) = %q, want %q", tt.s, tt.max, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"unicode/utf8"
+)
+
+// Redacted 敏感字段脱敏后的取值
+const Redacted = "[REDACTED]"
+
+// sensitiveKeys 值需要脱敏的字段名（不区分大小写）
+var sensitiveKeys = map[string]bool{
+	"auth_info":     true,
+	"password":      true,
+	"passwd":        true,
+	"dsn":           true,
+	"secret":        true,
+	"token":         true,
+	"access_token":  true,
+	"refresh_token": true,
+	"api_key":       true,
+	"apikey":        true,
+	"authorization": true,
+	"cookie":        true,
+}
+
+// IsSensitive 字段名是否需要脱敏，除 sensitiveKeys 外还包括 *_password、*_secret、*_token 形式的字段
+func IsSensitive(key string) bool {
+	key = strings.ToLower(key)
+	if sensitiveKeys[key] {
+		return true
+	}
+	for _, suffix := range []string{"_password", "_secret", "_token"} {
+		if strings.HasSuffix(key, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Redact 返回 v 的副本，其中 map 里敏感字段的值替换为 Redacted，嵌套的 map 和数组同样处理
+func Redact(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			if IsSensitive(key) {
+				out[key] = Redacted
+			} else {
+				out[key] = Redact(value)
+			}
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, value := range v {
+			out[i] = Redact(value)
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+// Truncate 截断超过 max 字节的字符串（不切断多字节字符），max 不大于0时不截断
+func Truncate(s string, max int) string {
+	if max <= 0 || len(s) <= max {
+		return s
+	}
+	cut := max
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return fmt.Sprintf("%s...(已截断，共%d字节)", s[:cut], len(s))
+}
+
+// Payload 把原始JSON报文包装为日志字段值：记录时解析并脱敏其中的敏感字段，不是合法JSON时原样输出
+// 长度由 logger 的 max_payload 限制
+func Payload(data []byte) slog.LogValuer {
+	return payload(data)
+}
+
+// payload 延迟到实际输出时才解析，级别被过滤的日志不产生开销
+type payload []byte
+
+// LogValue 实现 slog.LogValuer
+func (p payload) LogValue() slog.Value {
+	var v interface{}
+	decoder := json.NewDecoder(bytes.NewReader(p))
+	decoder.UseNumber()
+	if err := decoder.Decode(&v); err != nil {
+		return slog.StringValue(string(p))
+	}
+	data, err := json.Marshal(Redact(v))
+	if err != nil {
+		return slog.StringValue(string(p))
+	}
+	return slog.StringValue(string(data))
+}
@@ -4,23 +4,44 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"log"
-	"strconv"
+	"log/slog"
+	"mcp-ai-client/internal/logging"
+	"sync"
+	"sync/atomic"
 	"time"
 
-	"github.com/gorilla/websocket"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // MCPClient MCP客户端 - 专门用于AI工具演示
+// 所有请求共享一条 Transport 连接：由单个读取协程按ID分发响应；
+// 连接断开后按 ReconnectConfig 自动重连并重新握手
 type MCPClient struct {
-	conn    *websocket.Conn
-	timeout time.Duration
+	dialer    Dialer
+	timeout   time.Duration
+	reconnect ReconnectConfig
+	logger    *slog.Logger
+	lastID    atomic.Int64 // 最近分配的JSON-RPC请求ID，见 nextID
+
+	mu          sync.Mutex
+	conn        Transport
+	state       ConnectionState
+	ready       chan struct{} // 状态为 Connected 时关闭
+	pending     map[string]*pendingCall
+	initialized bool // 是否已完成过握手，重连后需要重新握手
+	lastErr     error
+	listeners   []func(ConnectionState)
+	observers   []CallObserver
+	closedCh    chan struct{}
+	progress    map[string]ProgressFunc // 按 progressToken 登记的进度回调
+
+	catalog toolCatalog
 }
 
 // MCPMessage MCP消息结构
 type MCPMessage struct {
 	JSONRPC string      `json:"jsonrpc"`
-	ID      interface{} `json:"id"`
+	ID      interface{} `json:"id,omitempty"`
 	Method  string      `json:"method,omitempty"`
 	Params  interface{} `json:"params,omitempty"`
 	Result  interface{} `json:"result,omitempty"`
@@ -40,45 +61,135 @@ type ToolCallParams struct {
 	Arguments map[string]interface{} `json:"arguments"`
 }
 
-// ToolCallResult 工具调用结果
-type ToolCallResult struct {
-	Content []Content `json:"content"`
-}
+// metaRequestID tools/call 请求 _meta 中携带发起方HTTP请求ID的键
+const metaRequestID = "x-request-id"
 
-// Content 内容结构
-type Content struct {
-	Type string `json:"type"`
-	Text string `json:"text"`
-}
+// NewMCPClient 创建MCP客户端并建立首条连接
+// dialer 决定传输方式（见 NewDialer），reconnect 为 nil 时不自动重连，logger 为 nil 时使用 slog.Default()
+// 收发的完整报文只在 debug 级别记录，且经过脱敏和截断
+func NewMCPClient(dialer Dialer, timeout time.Duration, reconnect *ReconnectConfig, logger *slog.Logger) (*MCPClient, error) {
+	c := &MCPClient{
+		dialer:   dialer,
+		timeout:  timeout,
+		logger:   logging.OrDefault(logger),
+		state:    StateConnecting,
+		ready:    make(chan struct{}),
+		pending:  make(map[string]*pendingCall),
+		closedCh: make(chan struct{}),
+		progress: make(map[string]ProgressFunc),
+	}
+	if reconnect != nil {
+		c.reconnect = reconnect.withDefaults()
+	}
 
-// NewMCPClient 创建MCP客户端
-func NewMCPClient(serverURL string, timeout time.Duration) (*MCPClient, error) {
-	dialer := websocket.Dialer{}
-	conn, _, err := dialer.Dial(serverURL, nil)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	conn, err := c.dialer(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("连接MCP服务器失败: %v", err)
+		return nil, &TransportError{Err: err}
 	}
 
-	log.Printf("MCP服务器连接成功: %s", serverURL)
-	return &MCPClient{
-		conn:    conn,
-		timeout: timeout,
-	}, nil
+	c.logger.Info("MCP服务器连接成功")
+	c.conn = conn
+	c.setStateLocked(StateConnected)
+	go c.readLoop(conn)
+	return c, nil
 }
 
-// Close 关闭连接
+// Close 关闭连接，所有等待中的请求以 ErrClientClosed 失败
 func (c *MCPClient) Close() error {
-	if c.conn != nil {
-		return c.conn.Close()
+	c.mu.Lock()
+	if c.state == StateClosed {
+		c.mu.Unlock()
+		return nil
+	}
+	notify := c.setStateLocked(StateClosed)
+	close(c.closedCh)
+	conn := c.conn
+	c.conn = nil
+	for key, call := range c.pending {
+		delete(c.pending, key)
+		call.ch <- callResult{err: ErrClientClosed}
+	}
+	c.mu.Unlock()
+	notify()
+
+	if conn != nil {
+		return conn.Close()
 	}
 	return nil
 }
 
-// Initialize 初始化MCP连接
+// Initialize 初始化MCP连接，失败时最多重试3次，ctx 结束时停止等待
+// 成功后客户端会记住已握手，断线重连时自动重新执行握手
 func (c *MCPClient) Initialize(ctx context.Context) error {
+	var lastErr error
+	for attempt := 0; attempt < 3; attempt++ {
+		if attempt > 0 {
+			c.logger.Warn("MCP初始化重试", "attempt", attempt+1, "max_attempts", 3, "error", lastErr)
+			timer := time.NewTimer(time.Duration(attempt) * time.Second)
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+				return fmt.Errorf("%v（重试被中止: %w）", lastErr, ctx.Err())
+			}
+		}
+
+		conn, err := c.waitReady(ctx)
+		if err != nil {
+			lastErr = fmt.Errorf("初始化失败: %w", err)
+			continue
+		}
+
+		if err := c.handshake(ctx, conn); err != nil {
+			lastErr = err
+			continue
+		}
+
+		c.mu.Lock()
+		c.initialized = true
+		c.mu.Unlock()
+		return nil
+	}
+
+	if lastErr == nil {
+		lastErr = fmt.Errorf("初始化失败: 未知错误")
+	}
+	return lastErr
+}
+
+// nextID 分配JSON-RPC请求ID：在客户端生命周期内从1开始严格递增，跨重连不复用，
+// 并发调用也不会重复；数值远小于 2^53，按 JSON number 解析的服务端不会丢失精度
+func (c *MCPClient) nextID() int64 {
+	return c.lastID.Add(1)
+}
+
+// Ping 发送 ping 请求确认服务端仍在响应，未连接时立即失败而不等待重连
+func (c *MCPClient) Ping(ctx context.Context) error {
+	if state := c.State(); state != StateConnected {
+		return &TransportError{Err: fmt.Errorf("MCP连接状态为 %s", state)}
+	}
+
+	response, err := c.sendMessage(ctx, MCPMessage{
+		JSONRPC: "2.0",
+		ID:      c.nextID(),
+		Method:  "ping",
+	})
+	if err != nil {
+		return err
+	}
+	if response.Error != nil {
+		return response.Error
+	}
+	return nil
+}
+
+// handshake 在指定连接上执行 initialize 请求并发送 notifications/initialized
+func (c *MCPClient) handshake(ctx context.Context, conn Transport) error {
 	initMsg := MCPMessage{
 		JSONRPC: "2.0",
-		ID:      1,
+		ID:      c.nextID(),
 		Method:  "initialize",
 		Params: map[string]interface{}{
 			"protocolVersion": "2024-11-05",
@@ -92,62 +203,101 @@ func (c *MCPClient) Initialize(ctx context.Context) error {
 		},
 	}
 
-	log.Printf("发送初始化消息: %+v", initMsg)
+	response, err := c.roundTrip(ctx, conn, initMsg, false)
+	if err != nil {
+		return fmt.Errorf("初始化失败: %w", err)
+	}
 
-	var lastErr error
-	for attempt := 0; attempt < 3; attempt++ {
-		if attempt > 0 {
-			log.Printf("初始化重试 %d/3", attempt+1)
-			time.Sleep(time.Duration(attempt) * time.Second)
+	if response.Error != nil {
+		// 如果错误是"已经初始化"，则认为是成功的
+		if response.Error.Code == -32000 && response.Error.Message == "Already initialized" {
+			c.logger.Info("MCP连接已经初始化，继续执行")
+			return nil
 		}
+		return fmt.Errorf("初始化错误: %w", response.Error)
+	}
 
-		response, err := c.sendMessage(ctx, initMsg)
-		if err != nil {
-			lastErr = fmt.Errorf("初始化失败: %v", err)
-			continue
-		}
+	if err := c.notify(conn, "notifications/initialized", nil); err != nil {
+		return fmt.Errorf("发送initialized通知失败: %v", err)
+	}
 
-		log.Printf("收到初始化响应: %+v", response)
+	// 新会话的工具集可能已变化（例如服务端升级后重启）
+	c.invalidateTools()
 
-		if response.Error != nil {
-			// 如果错误是"已经初始化"，则认为是成功的
-			if response.Error.Code == -32000 && response.Error.Message == "Already initialized" {
-				log.Println("MCP连接已经初始化，继续执行")
-				return nil
-			}
-			lastErr = fmt.Errorf("初始化错误: %d - %s", response.Error.Code, response.Error.Message)
-			continue
-		}
+	c.logger.Info("MCP连接初始化成功")
+	return nil
+}
 
-		log.Println("MCP连接初始化成功")
-		return nil
+// CallTool 调用MCP工具
+// 已知工具的 inputSchema 时先在本地校验参数，校验失败返回 *ValidationError；
+// 其余失败按来源区分：工具执行失败(isError)返回 *ToolError，JSON-RPC 错误响应返回 *MCPError，
+// 连接问题返回 *TransportError，超时返回 ErrTimeout，可用 ClassifyError 归类
+// opts 可通过 WithProgress 订阅服务端上报的执行进度；
+// 每次调用创建一个 tools/call span，ctx 中的 trace context 和请求ID（见 logging.WithRequestID）通过 _meta 传给服务端
+func (c *MCPClient) CallTool(ctx context.Context, toolName string, arguments map[string]interface{}, opts ...CallOption) (*ToolCallResult, error) {
+	c.mu.Lock()
+	observers := append([]CallObserver{}, c.observers...)
+	c.mu.Unlock()
+
+	ctx, span := startCallSpan(ctx, toolName, arguments)
+	for _, o := range observers {
+		o.ToolCallStarted(toolName)
+	}
+	start := time.Now()
+	result, err := c.callTool(ctx, toolName, arguments, opts...)
+	for _, o := range observers {
+		o.ToolCallFinished(toolName, time.Since(start), err)
+	}
+	endCallSpan(span, err)
+	return result, err
+}
+
+// callTool 执行工具调用
+func (c *MCPClient) callTool(ctx context.Context, toolName string, arguments map[string]interface{}, opts ...CallOption) (*ToolCallResult, error) {
+	var options callOptions
+	for _, opt := range opts {
+		opt(&options)
 	}
 
-	if lastErr == nil {
-		lastErr = fmt.Errorf("初始化失败: 未知错误")
+	if err := c.validateToolArguments(ctx, toolName, arguments); err != nil {
+		return nil, err
+	}
+
+	id := c.nextID()
+	trace.SpanFromContext(ctx).SetAttributes(attrRequestID.String(fmt.Sprint(id)))
+	params := map[string]interface{}{
+		"name":      toolName,
+		"arguments": arguments,
+	}
+	meta := map[string]interface{}{}
+	if options.onProgress != nil {
+		// 请求ID在客户端内唯一，直接用作 progressToken
+		meta["progressToken"] = id
+		defer c.registerProgress(id, options.onProgress)()
+	}
+	injectTraceContext(ctx, meta)
+	if requestID := logging.RequestID(ctx); requestID != "" {
+		// 与 HTTP 响应头 X-Request-ID 一致，便于服务端日志与本端请求对应
+		meta[metaRequestID] = requestID
+	}
+	if len(meta) > 0 {
+		params["_meta"] = meta
 	}
-	return lastErr
-}
 
-// CallTool 调用MCP工具
-func (c *MCPClient) CallTool(ctx context.Context, toolName string, arguments map[string]interface{}) (*ToolCallResult, error) {
 	callMsg := MCPMessage{
 		JSONRPC: "2.0",
-		ID:      time.Now().UnixNano(),
+		ID:      id,
 		Method:  "tools/call",
-		Params: map[string]interface{}{
-			"name":      toolName,
-			"arguments": arguments,
-		},
+		Params:  params,
 	}
 
 	response, err := c.sendMessage(ctx, callMsg)
 	if err != nil {
-		return nil, fmt.Errorf("调用工具失败: %v", err)
+		return nil, fmt.Errorf("调用工具失败: %w", err)
 	}
 
 	if response.Error != nil {
-		return nil, fmt.Errorf("工具调用错误: %d - %s", response.Error.Code, response.Error.Message)
+		return nil, fmt.Errorf("工具调用错误: %w", response.Error)
 	}
 
 	// 解析结果
@@ -161,121 +311,29 @@ func (c *MCPClient) CallTool(ctx context.Context, toolName string, arguments map
 		return nil, fmt.Errorf("解析工具结果失败: %v", err)
 	}
 
-	return &toolResult, nil
-}
-
-// isIDMatch 检查两个ID是否匹配
-func isIDMatch(id1, id2 interface{}) bool {
-	// 如果类型相同，直接比较
-	if id1 == id2 {
-		return true
-	}
-
-	// 处理数字类型的比较
-	switch v1 := id1.(type) {
-	case int:
-		if v2, ok := id2.(int); ok {
-			return v1 == v2
-		}
-		if v2, ok := id2.(float64); ok {
-			return float64(v1) == v2
-		}
-	case float64:
-		if v2, ok := id2.(float64); ok {
-			return v1 == v2
-		}
-		if v2, ok := id2.(int); ok {
-			return v1 == float64(v2)
-		}
-	case string:
-		if v2, ok := id2.(string); ok {
-			return v1 == v2
-		}
-	}
-
-	// 转换为字符串进行比较，处理科学计数法
-	str1 := fmt.Sprintf("%v", id1)
-	str2 := fmt.Sprintf("%v", id2)
-
-	// 如果都是数字字符串，转换为float64进行比较
-	if f1, err1 := strconv.ParseFloat(str1, 64); err1 == nil {
-		if f2, err2 := strconv.ParseFloat(str2, 64); err2 == nil {
-			return f1 == f2
-		}
+	if toolResult.IsError {
+		return nil, &ToolError{Tool: toolName, Result: &toolResult}
 	}
 
-	return str1 == str2
+	return &toolResult, nil
 }
 
-// sendMessage 发送消息并等待响应
-func (c *MCPClient) sendMessage(ctx context.Context, msg MCPMessage) (*MCPMessage, error) {
-	// 设置超时上下文
-	ctx, cancel := context.WithTimeout(ctx, c.timeout)
-	defer cancel()
-
-	// 序列化消息
-	msgBytes, err := json.Marshal(msg)
+// validateToolArguments 按工具目录中的 inputSchema 校验参数
+// 获取不到工具目录或工具未知时跳过本地校验，交由服务端判断
+func (c *MCPClient) validateToolArguments(ctx context.Context, toolName string, arguments map[string]interface{}) error {
+	tool, ok, err := c.GetTool(ctx, toolName)
 	if err != nil {
-		return nil, fmt.Errorf("序列化消息失败: %v", err)
+		c.logger.WarnContext(ctx, "获取工具目录失败，跳过参数校验", "tool", toolName, "error", err)
+		return nil
 	}
-
-	log.Printf("发送MCP消息: %s", string(msgBytes))
-
-	// 发送消息
-	if err := c.conn.WriteMessage(websocket.TextMessage, msgBytes); err != nil {
-		return nil, fmt.Errorf("发送消息失败: %v", err)
+	if !ok {
+		return nil
 	}
 
-	log.Printf("消息发送成功，等待响应...")
-
-	// 等待响应
-	responseChan := make(chan *MCPMessage, 1)
-	errorChan := make(chan error, 1)
-
-	go func() {
-		// 读取响应，直到找到匹配的ID
-		for {
-			log.Printf("等待读取WebSocket消息...")
-			_, message, err := c.conn.ReadMessage()
-			if err != nil {
-				log.Printf("WebSocket读取错误: %v", err)
-				errorChan <- fmt.Errorf("读取响应失败: %v", err)
-				return
-			}
-
-			log.Printf("收到MCP响应: %s", string(message))
-
-			var response MCPMessage
-			if err := json.Unmarshal(message, &response); err != nil {
-				log.Printf("解析响应失败: %v", err)
-				continue // 继续读取下一个消息
-			}
-
-			log.Printf("解析后的响应: ID=%v, Method=%s, Error=%v", response.ID, response.Method, response.Error)
-
-			// 检查是否是我们要的响应（ID匹配）
-			if isIDMatch(msg.ID, response.ID) {
-				log.Printf("找到匹配的响应ID: %v", response.ID)
-				responseChan <- &response
-				return
-			} else {
-				log.Printf("收到不匹配的响应ID: 期望 %v, 实际 %v", msg.ID, response.ID)
-			}
-		}
-	}()
-
-	// 等待响应或超时
-	select {
-	case response := <-responseChan:
-		log.Printf("成功收到响应")
-		return response, nil
-	case err := <-errorChan:
-		log.Printf("读取响应时发生错误: %v", err)
-		return nil, err
-	case <-ctx.Done():
-		log.Printf("等待响应超时")
-		return nil, fmt.Errorf("等待响应超时")
+	if fieldErrors := ValidateArguments(tool.InputSchema, arguments); len(fieldErrors) > 0 {
+		return &ValidationError{Tool: toolName, Errors: fieldErrors}
 	}
+	return nil
 }
 
 // AI工具方法 - 集成5种AI工具功能 (5.1-5.5)
@@ -296,14 +354,15 @@ func (c *MCPClient) CallAIChat(ctx context.Context, prompt string, provider stri
 
 	result, err := c.CallTool(ctx, "ai_chat", args)
 	if err != nil {
-		return "", fmt.Errorf("AI聊天调用失败: %v", err)
+		return "", fmt.Errorf("AI聊天调用失败: %w", err)
 	}
 
-	if len(result.Content) == 0 {
+	text := result.Text()
+	if text == "" {
 		return "", fmt.Errorf("AI聊天结果为空")
 	}
 
-	return result.Content[0].Text, nil
+	return text, nil
 }
 
 // CallAIFileManager 调用AI文件管理工具 (5.2)
@@ -316,14 +375,15 @@ func (c *MCPClient) CallAIFileManager(ctx context.Context, instruction string, t
 
 	result, err := c.CallTool(ctx, "ai_file_manager", args)
 	if err != nil {
-		return "", fmt.Errorf("AI文件管理调用失败: %v", err)
+		return "", fmt.Errorf("AI文件管理调用失败: %w", err)
 	}
 
-	if len(result.Content) == 0 {
+	text := result.Text()
+	if text == "" {
 		return "", fmt.Errorf("AI文件管理结果为空")
 	}
 
-	return result.Content[0].Text, nil
+	return text, nil
 }
 
 // CallAIDataProcessor 调用AI数据处理工具 (5.3)
@@ -338,14 +398,15 @@ func (c *MCPClient) CallAIDataProcessor(ctx context.Context, instruction string,
 
 	result, err := c.CallTool(ctx, "ai_data_processor", args)
 	if err != nil {
-		return "", fmt.Errorf("AI数据处理调用失败: %v", err)
+		return "", fmt.Errorf("AI数据处理调用失败: %w", err)
 	}
 
-	if len(result.Content) == 0 {
+	text := result.Text()
+	if text == "" {
 		return "", fmt.Errorf("AI数据处理结果为空")
 	}
 
-	return result.Content[0].Text, nil
+	return text, nil
 }
 
 // CallAIAPIClient 调用AI网络请求工具 (5.4)
@@ -359,14 +420,15 @@ func (c *MCPClient) CallAIAPIClient(ctx context.Context, instruction string, bas
 
 	result, err := c.CallTool(ctx, "ai_api_client", args)
 	if err != nil {
-		return "", fmt.Errorf("AI网络请求调用失败: %v", err)
+		return "", fmt.Errorf("AI网络请求调用失败: %w", err)
 	}
 
-	if len(result.Content) == 0 {
+	text := result.Text()
+	if text == "" {
 		return "", fmt.Errorf("AI网络请求结果为空")
 	}
 
-	return result.Content[0].Text, nil
+	return text, nil
 }
 
 // CallAIQueryWithAnalysis 调用AI数据库查询工具 (5.5)
@@ -383,12 +445,13 @@ func (c *MCPClient) CallAIQueryWithAnalysis(ctx context.Context, description str
 
 	result, err := c.CallTool(ctx, "ai_query_with_analysis", args)
 	if err != nil {
-		return "", fmt.Errorf("AI数据库查询调用失败: %v", err)
+		return "", fmt.Errorf("AI数据库查询调用失败: %w", err)
 	}
 
-	if len(result.Content) == 0 {
+	text := result.Text()
+	if text == "" {
 		return "", fmt.Errorf("AI数据库查询结果为空")
 	}
 
-	return result.Content[0].Text, nil
+	return text, nil
 }
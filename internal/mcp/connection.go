@@ -0,0 +1,579 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"mcp-ai-client/internal/logging"
+	"strconv"
+	"time"
+)
+
+// 在途请求在断线时的处理策略
+const (
+	InflightFail   = "fail"   // 立即以 ErrConnectionLost 失败
+	InflightReplay = "replay" // 重连并完成握手后重新发送；tools/call 仅重放 ReplayTools 中的工具
+)
+
+var (
+	// ErrConnectionLost 连接在等待响应期间断开
+	ErrConnectionLost = errors.New("MCP连接已断开")
+	// ErrClientClosed 客户端已被关闭
+	ErrClientClosed = errors.New("MCP客户端已关闭")
+)
+
+// ReconnectConfig 断线重连配置
+type ReconnectConfig struct {
+	Enabled        bool          `yaml:"enabled"`
+	InitialBackoff time.Duration `yaml:"initial_backoff"` // 首次重连等待时间，默认500ms
+	MaxBackoff     time.Duration `yaml:"max_backoff"`     // 退避上限，默认30s
+	MaxAttempts    int           `yaml:"max_attempts"`    // 单轮最多重试次数，0表示不限
+	InflightPolicy string        `yaml:"inflight_policy"` // fail 或 replay，默认 fail
+	ReplayTools    []string      `yaml:"replay_tools"`    // replay 策略下允许重放的幂等工具名，其余工具调用断线时以 ErrConnectionLost 失败
+}
+
+// withDefaults 填充未配置的重连参数
+func (rc ReconnectConfig) withDefaults() ReconnectConfig {
+	if rc.InitialBackoff <= 0 {
+		rc.InitialBackoff = 500 * time.Millisecond
+	}
+	if rc.MaxBackoff <= 0 {
+		rc.MaxBackoff = 30 * time.Second
+	}
+	if rc.MaxBackoff < rc.InitialBackoff {
+		rc.MaxBackoff = rc.InitialBackoff
+	}
+	if rc.InflightPolicy != InflightReplay {
+		rc.InflightPolicy = InflightFail
+	}
+	return rc
+}
+
+// backoff 计算第 attempt 次重连前的等待时间：指数退避 + 随机抖动
+func (rc ReconnectConfig) backoff(attempt int) time.Duration {
+	d := rc.InitialBackoff
+	for i := 0; i < attempt && d < rc.MaxBackoff; i++ {
+		d *= 2
+	}
+	if d > rc.MaxBackoff {
+		d = rc.MaxBackoff
+	}
+	// 在 [d/2, d] 区间内随机，避免多个实例同时重连
+	half := d / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+// replayable 断线后请求能否在重连后重放：须启用 replay 策略；
+// tools/call 仅限 ReplayTools 中的工具，其他请求（如 tools/list、ping）没有副作用，总是可以重放
+func (rc ReconnectConfig) replayable(msg MCPMessage) bool {
+	if !rc.Enabled || rc.InflightPolicy != InflightReplay {
+		return false
+	}
+	if msg.Method != "tools/call" {
+		return true
+	}
+	params, _ := msg.Params.(map[string]interface{})
+	name, _ := params["name"].(string)
+	for _, tool := range rc.ReplayTools {
+		if tool == name {
+			return true
+		}
+	}
+	return false
+}
+
+// ConnectionState 连接状态
+type ConnectionState int
+
+const (
+	StateDisconnected ConnectionState = iota
+	StateConnecting
+	StateConnected
+	StateClosed
+)
+
+// String 返回状态名称
+func (s ConnectionState) String() string {
+	switch s {
+	case StateDisconnected:
+		return "disconnected"
+	case StateConnecting:
+		return "connecting"
+	case StateConnected:
+		return "connected"
+	case StateClosed:
+		return "closed"
+	default:
+		return "unknown"
+	}
+}
+
+// callResult 读取协程交给等待者的结果
+type callResult struct {
+	msg *MCPMessage
+	err error
+}
+
+// pendingCall 等待响应的请求
+type pendingCall struct {
+	msg        MCPMessage
+	ch         chan callResult
+	replayable bool // 断线后保留并在重连后重放，见 ReconnectConfig.replayable
+}
+
+// State 返回当前连接状态
+func (c *MCPClient) State() ConnectionState {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.state
+}
+
+// LastError 返回最近一次导致断线或重连失败的错误
+func (c *MCPClient) LastError() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lastErr
+}
+
+// OnStateChange 注册连接状态变化回调，回调在状态切换后同步调用
+func (c *MCPClient) OnStateChange(fn func(ConnectionState)) {
+	c.mu.Lock()
+	c.listeners = append(c.listeners, fn)
+	c.mu.Unlock()
+}
+
+// CallObserver 观察工具调用（用于统计指标），方法在调用方协程中同步执行，不能阻塞
+type CallObserver interface {
+	ToolCallStarted(tool string)
+	ToolCallFinished(tool string, duration time.Duration, err error)
+}
+
+// ObserveCalls 注册工具调用观察者
+func (c *MCPClient) ObserveCalls(o CallObserver) {
+	c.mu.Lock()
+	c.observers = append(c.observers, o)
+	c.mu.Unlock()
+}
+
+// setStateLocked 切换状态并维护 ready 通道，返回需要在解锁后执行的通知函数
+func (c *MCPClient) setStateLocked(s ConnectionState) func() {
+	old := c.state
+	if old == s {
+		return func() {}
+	}
+	c.state = s
+	if s == StateConnected {
+		close(c.ready)
+	} else if old == StateConnected {
+		c.ready = make(chan struct{})
+	}
+
+	listeners := append([]func(ConnectionState){}, c.listeners...)
+	return func() {
+		c.logger.Info("MCP连接状态变化", "from", old.String(), "to", s.String())
+		for _, fn := range listeners {
+			fn(s)
+		}
+	}
+}
+
+// idKey 将JSON-RPC ID规范化为待处理请求表的键
+// 数字ID按float64取值比较：以双精度解析JSON的服务端（如JavaScript）回传的大整数ID会丢失精度，
+// 与原先 isIDMatch 的比较语义保持一致；字符串ID加前缀以免与数字ID冲突
+func idKey(id interface{}) string {
+	switch v := id.(type) {
+	case nil:
+		return ""
+	case string:
+		return "s:" + v
+	}
+	if f, ok := toFloat(id); ok {
+		return strconv.FormatFloat(f, 'g', -1, 64)
+	}
+	return fmt.Sprintf("%v", id)
+}
+
+// readLoop 每条连接唯一的读取协程：持续读取消息并按ID分发给等待中的调用方
+func (c *MCPClient) readLoop(conn Transport) {
+	for {
+		data, err := conn.Receive()
+		if err != nil {
+			c.handleDisconnect(conn, err)
+			return
+		}
+
+		c.logger.Debug("收到MCP消息", "payload", logging.Payload(data))
+
+		var msg MCPMessage
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+		if err := decoder.Decode(&msg); err != nil {
+			c.logger.Warn("解析MCP消息失败", "error", err, "payload", logging.Payload(data))
+			continue
+		}
+
+		if msg.Method != "" {
+			c.handleServerMessage(conn, &msg)
+			continue
+		}
+
+		c.dispatch(&msg)
+	}
+}
+
+// dispatch 将响应交给对应ID的等待者
+func (c *MCPClient) dispatch(msg *MCPMessage) {
+	key := idKey(msg.ID)
+
+	c.mu.Lock()
+	call, ok := c.pending[key]
+	if ok {
+		delete(c.pending, key)
+	}
+	c.mu.Unlock()
+
+	if !ok {
+		c.logger.Info("丢弃未知或已取消请求的响应", "rpc_id", msg.ID)
+		return
+	}
+	call.ch <- callResult{msg: msg}
+}
+
+// handleServerMessage 处理服务端主动发来的请求和通知
+func (c *MCPClient) handleServerMessage(conn Transport, msg *MCPMessage) {
+	// 没有ID的是通知，无需回复
+	if msg.ID == nil {
+		c.logger.Debug("收到MCP通知", "method", msg.Method)
+		switch msg.Method {
+		case "notifications/tools/list_changed":
+			c.onToolsListChanged()
+		case "notifications/progress":
+			c.onProgress(msg.Params)
+		}
+		return
+	}
+
+	reply := MCPMessage{JSONRPC: "2.0", ID: msg.ID}
+	switch msg.Method {
+	case "ping":
+		reply.Result = map[string]interface{}{}
+	default:
+		reply.Error = &MCPError{Code: -32601, Message: "Method not found: " + msg.Method}
+	}
+
+	if err := c.writeMessage(context.Background(), conn, reply); err != nil {
+		c.logger.Warn("回复服务端请求失败", "method", msg.Method, "error", err)
+	}
+}
+
+// handleDisconnect 处理连接断开：按策略处理在途请求并启动重连
+func (c *MCPClient) handleDisconnect(conn Transport, err error) {
+	c.mu.Lock()
+	if conn != c.conn {
+		// 已被替换或关闭的旧连接
+		c.mu.Unlock()
+		return
+	}
+	c.conn = nil
+	// stdio 关闭时需等待子进程退出，放在释放锁之后，避免阻塞其他调用
+	defer conn.Close()
+
+	if c.state == StateClosed {
+		c.mu.Unlock()
+		return
+	}
+
+	c.logger.Warn("MCP连接断开", "error", err)
+	c.lastErr = fmt.Errorf("%w: %v", ErrConnectionLost, err)
+
+	for key, call := range c.pending {
+		if call.replayable {
+			continue
+		}
+		delete(c.pending, key)
+		call.ch <- callResult{err: c.lastErr}
+	}
+
+	var notify func()
+	if !c.reconnect.Enabled {
+		notify = c.setStateLocked(StateDisconnected)
+	} else if c.state != StateConnecting {
+		// 处于 Connecting 说明重连协程正在握手，由它负责下一次尝试
+		notify = c.setStateLocked(StateConnecting)
+		go c.reconnectLoop()
+	} else {
+		notify = func() {}
+	}
+	c.mu.Unlock()
+	notify()
+}
+
+// reconnectLoop 按指数退避重连，成功后重新握手并重放保留的请求
+func (c *MCPClient) reconnectLoop() {
+	for attempt := 0; c.reconnect.MaxAttempts <= 0 || attempt < c.reconnect.MaxAttempts; attempt++ {
+		wait := c.reconnect.backoff(attempt)
+		c.logger.Info("MCP等待重连", "wait", wait, "attempt", attempt+1)
+		select {
+		case <-time.After(wait):
+		case <-c.closedCh:
+			return
+		}
+
+		if err := c.reconnectOnce(); err != nil {
+			c.logger.Warn("MCP重连失败", "attempt", attempt+1, "error", err)
+			c.mu.Lock()
+			c.lastErr = err
+			c.mu.Unlock()
+			continue
+		}
+
+		c.logger.Info("MCP重连成功", "attempt", attempt+1)
+		return
+	}
+
+	// 本轮重试耗尽：剩余请求全部失败，等待下一次调用触发新一轮重连
+	c.mu.Lock()
+	for key, call := range c.pending {
+		delete(c.pending, key)
+		call.ch <- callResult{err: c.lastErr}
+	}
+	notify := func() {}
+	if c.state == StateConnecting {
+		notify = c.setStateLocked(StateDisconnected)
+	}
+	c.mu.Unlock()
+	notify()
+}
+
+// reconnectOnce 建立连接并在需要时重新执行初始化握手
+func (c *MCPClient) reconnectOnce() error {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	conn, err := c.dialer(ctx)
+	if err != nil {
+		return &TransportError{Err: err}
+	}
+
+	c.mu.Lock()
+	if c.state == StateClosed {
+		c.mu.Unlock()
+		conn.Close()
+		return ErrClientClosed
+	}
+	c.conn = conn
+	initialized := c.initialized
+	c.mu.Unlock()
+
+	go c.readLoop(conn)
+
+	if initialized {
+		if err := c.handshake(ctx, conn); err != nil {
+			c.mu.Lock()
+			if c.conn == conn {
+				c.conn = nil
+			}
+			c.mu.Unlock()
+			conn.Close()
+			return err
+		}
+	}
+
+	c.mu.Lock()
+	if c.conn != conn {
+		c.mu.Unlock()
+		return ErrConnectionLost
+	}
+	notify := c.setStateLocked(StateConnected)
+	var replay []MCPMessage
+	for _, call := range c.pending {
+		if call.replayable {
+			replay = append(replay, call.msg)
+		}
+	}
+	c.mu.Unlock()
+	notify()
+
+	for _, msg := range replay {
+		c.logger.Info("重放在途请求", "rpc_id", msg.ID, "method", msg.Method)
+		if err := c.writeMessage(context.Background(), conn, msg); err != nil {
+			c.logger.Warn("重放请求失败", "rpc_id", msg.ID, "error", err)
+		}
+	}
+	return nil
+}
+
+// waitReady 等待连接可用；断线且允许重连时会触发新一轮重连
+func (c *MCPClient) waitReady(ctx context.Context) (Transport, error) {
+	for {
+		c.mu.Lock()
+		switch c.state {
+		case StateClosed:
+			c.mu.Unlock()
+			return nil, ErrClientClosed
+		case StateConnected:
+			conn := c.conn
+			c.mu.Unlock()
+			return conn, nil
+		case StateDisconnected:
+			if !c.reconnect.Enabled {
+				err := c.lastErr
+				c.mu.Unlock()
+				return nil, err
+			}
+			notify := c.setStateLocked(StateConnecting)
+			go c.reconnectLoop()
+			c.mu.Unlock()
+			notify()
+			continue
+		}
+		ready := c.ready
+		c.mu.Unlock()
+
+		select {
+		case <-ready:
+		case <-c.closedCh:
+			return nil, ErrClientClosed
+		case <-ctx.Done():
+			if ctx.Err() == context.Canceled {
+				return nil, fmt.Errorf("等待MCP重连时请求被取消: %w", ctx.Err())
+			}
+			return nil, &TransportError{Err: fmt.Errorf("等待MCP重连超时: %v", c.LastError())}
+		}
+	}
+}
+
+// writeMessage 向指定连接写入一条消息，并发安全由 Transport 保证
+// ctx 用于日志关联请求ID，不控制写入本身
+func (c *MCPClient) writeMessage(ctx context.Context, conn Transport, msg MCPMessage) error {
+	msgBytes, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("序列化消息失败: %v", err)
+	}
+
+	c.logger.DebugContext(ctx, "发送MCP消息", "payload", logging.Payload(msgBytes))
+
+	if err := conn.Send(msgBytes); err != nil {
+		return &TransportError{Err: fmt.Errorf("发送消息失败: %v", err)}
+	}
+	return nil
+}
+
+// sendMessage 等待连接可用后发送请求，并等待读取协程分发的响应
+func (c *MCPClient) sendMessage(ctx context.Context, msg MCPMessage) (*MCPMessage, error) {
+	// 设置超时上下文
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	conn, err := c.waitReady(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return c.roundTrip(ctx, conn, msg, c.reconnect.replayable(msg))
+}
+
+// roundTrip 在指定连接上登记待处理请求、写出消息并等待响应
+func (c *MCPClient) roundTrip(ctx context.Context, conn Transport, msg MCPMessage, replayable bool) (*MCPMessage, error) {
+	key := idKey(msg.ID)
+	call := &pendingCall{msg: msg, ch: make(chan callResult, 1), replayable: replayable}
+
+	c.mu.Lock()
+	if c.conn != conn {
+		c.mu.Unlock()
+		return nil, ErrConnectionLost
+	}
+	if _, exists := c.pending[key]; exists {
+		c.mu.Unlock()
+		return nil, fmt.Errorf("请求ID冲突: %v", msg.ID)
+	}
+	c.pending[key] = call
+	c.mu.Unlock()
+
+	if err := c.writeMessage(ctx, conn, msg); err != nil {
+		// 写失败通常意味着连接已断开，重放策略下交给重连流程处理
+		if !replayable {
+			c.removePending(key)
+			return nil, err
+		}
+		c.logger.WarnContext(ctx, "发送失败，等待重连后重放", "rpc_id", msg.ID, "error", err)
+	}
+
+	select {
+	case res := <-call.ch:
+		return res.msg, res.err
+	case <-ctx.Done():
+		if c.removePending(key) && msg.Method != "initialize" {
+			// 服务端可能仍在执行：通知其放弃，之后迟到的响应因ID已不在待处理表中被丢弃
+			go c.cancelRequest(msg.ID, ctx.Err().Error())
+		}
+		if ctx.Err() == context.Canceled {
+			c.logger.InfoContext(ctx, "请求已取消", "rpc_id", msg.ID, "method", msg.Method)
+			return nil, fmt.Errorf("等待响应时请求被取消: %w", ctx.Err())
+		}
+		c.logger.WarnContext(ctx, "等待响应超时", "rpc_id", msg.ID, "method", msg.Method, "timeout", c.timeout)
+		return nil, ErrTimeout
+	}
+}
+
+// removePending 放弃等待某个请求的响应，返回请求此前是否仍在等待
+func (c *MCPClient) removePending(key string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, ok := c.pending[key]
+	delete(c.pending, key)
+	return ok
+}
+
+// CancelPending 放弃所有等待中的请求：逐个通知服务端取消，等待方以 ErrClientClosed 失败，返回放弃的请求数
+// 用于服务关闭时中止超出等待时间的请求
+func (c *MCPClient) CancelPending(reason string) int {
+	c.mu.Lock()
+	conn := c.conn
+	calls := c.pending
+	c.pending = make(map[string]*pendingCall)
+	c.mu.Unlock()
+
+	for _, call := range calls {
+		if conn != nil && call.msg.Method != "initialize" {
+			c.sendCancel(conn, call.msg.ID, reason)
+		}
+		call.ch <- callResult{err: ErrClientClosed}
+	}
+	return len(calls)
+}
+
+// cancelRequest 在当前连接上发送 notifications/cancelled
+// 断线期间无需发送：重连后是新会话，服务端不会再处理旧请求
+func (c *MCPClient) cancelRequest(id interface{}, reason string) {
+	c.mu.Lock()
+	conn := c.conn
+	c.mu.Unlock()
+	if conn == nil {
+		return
+	}
+	c.sendCancel(conn, id, reason)
+}
+
+// sendCancel 在指定连接上发送 notifications/cancelled
+func (c *MCPClient) sendCancel(conn Transport, id interface{}, reason string) {
+	c.logger.Info("通知MCP服务器取消请求", "rpc_id", id, "reason", reason)
+	params := map[string]interface{}{
+		"requestId": id,
+		"reason":    reason,
+	}
+	if err := c.notify(conn, "notifications/cancelled", params); err != nil {
+		c.logger.Warn("发送取消通知失败", "rpc_id", id, "error", err)
+	}
+}
+
+// notify 向指定连接发送通知（无ID、无响应）
+func (c *MCPClient) notify(conn Transport, method string, params interface{}) error {
+	return c.writeMessage(context.Background(), conn, MCPMessage{
+		JSONRPC: "2.0",
+		Method:  method,
+		Params:  params,
+	})
+}
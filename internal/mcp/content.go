@@ -0,0 +1,97 @@
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// 内容块类型
+const (
+	ContentText         = "text"
+	ContentImage        = "image"
+	ContentAudio        = "audio"
+	ContentResourceLink = "resource_link"
+	ContentResource     = "resource"
+)
+
+// ToolCallResult 工具调用结果
+type ToolCallResult struct {
+	Content           []Content   `json:"content"`
+	StructuredContent interface{} `json:"structuredContent,omitempty"`
+	IsError           bool        `json:"isError,omitempty"`
+}
+
+// Content 内容块，按 Type 使用不同字段：
+// text 使用 Text；image/audio 使用 Data(base64) 和 MimeType；
+// resource_link 使用 URI/Name/Description/MimeType；resource 使用 Resource
+type Content struct {
+	Type string `json:"type"`
+	Text string `json:"text,omitempty"`
+
+	Data     string `json:"data,omitempty"`
+	MimeType string `json:"mimeType,omitempty"`
+
+	URI         string `json:"uri,omitempty"`
+	Name        string `json:"name,omitempty"`
+	Description string `json:"description,omitempty"`
+
+	Resource    *EmbeddedResource      `json:"resource,omitempty"`
+	Annotations map[string]interface{} `json:"annotations,omitempty"`
+}
+
+// EmbeddedResource 嵌入的资源内容，文本资源使用 Text，二进制资源使用 Blob(base64)
+type EmbeddedResource struct {
+	URI      string `json:"uri"`
+	MimeType string `json:"mimeType,omitempty"`
+	Text     string `json:"text,omitempty"`
+	Blob     string `json:"blob,omitempty"`
+}
+
+// DataURL 返回 image/audio 块的 data URL，便于前端直接展示
+func (c Content) DataURL() string {
+	if c.Data == "" {
+		return ""
+	}
+	return "data:" + c.MimeType + ";base64," + c.Data
+}
+
+// Text 按顺序拼接所有文本块，没有文本块时返回空字符串
+func (r *ToolCallResult) Text() string {
+	var parts []string
+	for _, block := range r.Content {
+		if block.Type == ContentText && block.Text != "" {
+			parts = append(parts, block.Text)
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+// NonText 返回除文本外的全部内容块（图片、音频、资源等）
+func (r *ToolCallResult) NonText() []Content {
+	var blocks []Content
+	for _, block := range r.Content {
+		if block.Type != ContentText {
+			blocks = append(blocks, block)
+		}
+	}
+	return blocks
+}
+
+// DecodeStructured 将结构化结果解码到 v
+// 优先使用 structuredContent，缺失时退回将文本内容按JSON解析（兼容旧版服务端）
+func (r *ToolCallResult) DecodeStructured(v interface{}) error {
+	if r.StructuredContent != nil {
+		data, err := json.Marshal(r.StructuredContent)
+		if err != nil {
+			return fmt.Errorf("序列化structuredContent失败: %v", err)
+		}
+		return json.Unmarshal(data, v)
+	}
+
+	text := r.Text()
+	if text == "" {
+		return fmt.Errorf("工具结果不包含结构化内容")
+	}
+	return json.Unmarshal([]byte(text), v)
+}
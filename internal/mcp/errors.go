@@ -0,0 +1,95 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrTimeout 在超时时间内未收到MCP服务器的响应
+var ErrTimeout = errors.New("等待MCP响应超时")
+
+// 错误分类，供上层映射HTTP状态码和统计使用
+const (
+	ErrorClassValidation = "validation" // 参数未通过 inputSchema 校验
+	ErrorClassTool       = "tool"       // 工具已执行但返回 isError: true
+	ErrorClassProtocol   = "protocol"   // 服务端返回 JSON-RPC 错误响应
+	ErrorClassTimeout    = "timeout"    // 等待响应超时
+	ErrorClassCanceled   = "canceled"   // 调用方取消了请求
+	ErrorClassTransport  = "transport"  // 连接建立、收发失败或连接断开
+	ErrorClassInternal   = "internal"   // 其他错误，例如结果无法解析
+)
+
+// JSON-RPC 标准错误码
+const (
+	CodeParseError     = -32700
+	CodeInvalidRequest = -32600
+	CodeMethodNotFound = -32601
+	CodeInvalidParams  = -32602
+	CodeInternalError  = -32603
+)
+
+// Error 实现 error 接口，JSON-RPC 错误响应即协议错误
+func (e *MCPError) Error() string {
+	return fmt.Sprintf("%d - %s", e.Code, e.Message)
+}
+
+// ToolError 工具已执行但失败：服务端返回正常结果且 isError 为 true
+// Result 保留完整结果，错误详情通常在其文本内容中
+type ToolError struct {
+	Tool   string
+	Result *ToolCallResult
+}
+
+// Error 实现 error 接口
+func (e *ToolError) Error() string {
+	msg := e.Result.Text()
+	if msg == "" {
+		msg = "未返回错误详情"
+	}
+	return fmt.Sprintf("工具 %s 执行失败: %s", e.Tool, msg)
+}
+
+// TransportError 传输层错误：建立连接、发送消息失败或连接断开
+type TransportError struct {
+	Err error
+}
+
+// Error 实现 error 接口
+func (e *TransportError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap 返回底层错误
+func (e *TransportError) Unwrap() error {
+	return e.Err
+}
+
+// ClassifyError 返回错误所属的分类（ErrorClass* 常量），err 为 nil 时返回空字符串
+func ClassifyError(err error) string {
+	var (
+		validationErr *ValidationError
+		toolErr       *ToolError
+		protocolErr   *MCPError
+		transportErr  *TransportError
+	)
+
+	switch {
+	case err == nil:
+		return ""
+	case errors.As(err, &validationErr):
+		return ErrorClassValidation
+	case errors.As(err, &toolErr):
+		return ErrorClassTool
+	case errors.As(err, &protocolErr):
+		return ErrorClassProtocol
+	case errors.Is(err, context.Canceled):
+		return ErrorClassCanceled
+	case errors.Is(err, ErrTimeout), errors.Is(err, context.DeadlineExceeded):
+		return ErrorClassTimeout
+	case errors.As(err, &transportErr), errors.Is(err, ErrConnectionLost), errors.Is(err, ErrClientClosed):
+		return ErrorClassTransport
+	default:
+		return ErrorClassInternal
+	}
+}
@@ -0,0 +1,71 @@
+package mcp
+
+import (
+	"encoding/json"
+)
+
+// Progress 服务端通过 notifications/progress 上报的进度
+// Total 为 0 表示总量未知
+type Progress struct {
+	Progress float64 `json:"progress"`
+	Total    float64 `json:"total,omitempty"`
+	Message  string  `json:"message,omitempty"`
+}
+
+// ProgressFunc 进度回调，在读取协程中同步调用，实现不能阻塞
+type ProgressFunc func(Progress)
+
+// CallOption 单次工具调用的可选参数
+type CallOption func(*callOptions)
+
+type callOptions struct {
+	onProgress ProgressFunc
+}
+
+// WithProgress 为调用附带 progressToken，服务端上报的进度交给 fn 处理
+func WithProgress(fn ProgressFunc) CallOption {
+	return func(o *callOptions) {
+		o.onProgress = fn
+	}
+}
+
+// progressParams notifications/progress 的参数
+type progressParams struct {
+	ProgressToken interface{} `json:"progressToken"`
+	Progress
+}
+
+// registerProgress 登记进度回调，返回注销函数
+func (c *MCPClient) registerProgress(token interface{}, fn ProgressFunc) func() {
+	key := idKey(token)
+	c.mu.Lock()
+	c.progress[key] = fn
+	c.mu.Unlock()
+	return func() {
+		c.mu.Lock()
+		delete(c.progress, key)
+		c.mu.Unlock()
+	}
+}
+
+// onProgress 处理 notifications/progress，按 progressToken 分发给对应调用的回调
+// 调用已结束或未登记的进度直接丢弃
+func (c *MCPClient) onProgress(params interface{}) {
+	data, err := json.Marshal(params)
+	if err != nil {
+		c.logger.Warn("解析进度通知失败", "error", err)
+		return
+	}
+	var p progressParams
+	if err := json.Unmarshal(data, &p); err != nil {
+		c.logger.Warn("解析进度通知失败", "error", err)
+		return
+	}
+
+	c.mu.Lock()
+	fn := c.progress[idKey(p.ProgressToken)]
+	c.mu.Unlock()
+	if fn != nil {
+		fn(p.Progress)
+	}
+}
@@ -0,0 +1,300 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeServer 进程内的MCP服务端，通过 fakeConn 与客户端交换消息
+// 第一条连接上收到的 tools/call 不回复，用于模拟调用进行中连接断开
+type fakeServer struct {
+	mu       sync.Mutex
+	conns    []*fakeConn
+	requests []fakeRequest
+	held     chan string // 第一条连接上被挂起的工具名
+}
+
+// fakeRequest 服务端收到的一条消息
+type fakeRequest struct {
+	conn   int
+	method string
+	tool   string
+}
+
+func newFakeServer() *fakeServer {
+	return &fakeServer{held: make(chan string, 8)}
+}
+
+// dial 作为 Dialer 使用，每次调用建立一条新连接
+func (s *fakeServer) dial(ctx context.Context) (Transport, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	conn := &fakeConn{server: s, index: len(s.conns), recv: make(chan []byte, 16), closed: make(chan struct{})}
+	s.conns = append(s.conns, conn)
+	return conn, nil
+}
+
+// drop 断开指定连接，客户端的 Receive 返回错误
+func (s *fakeServer) drop(index int) {
+	s.mu.Lock()
+	conn := s.conns[index]
+	s.mu.Unlock()
+	conn.Close()
+}
+
+// count 返回收到某方法的次数，conn 为 -1 时统计所有连接
+func (s *fakeServer) count(conn int, method string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for _, r := range s.requests {
+		if r.method == method && (conn < 0 || r.conn == conn) {
+			n++
+		}
+	}
+	return n
+}
+
+// handle 按方法回复请求
+func (s *fakeServer) handle(conn *fakeConn, msg MCPMessage) {
+	params, _ := msg.Params.(map[string]interface{})
+	tool, _ := params["name"].(string)
+	s.mu.Lock()
+	s.requests = append(s.requests, fakeRequest{conn: conn.index, method: msg.Method, tool: tool})
+	s.mu.Unlock()
+
+	if msg.ID == nil {
+		return
+	}
+	reply := MCPMessage{JSONRPC: "2.0", ID: msg.ID}
+	switch msg.Method {
+	case "initialize":
+		reply.Result = map[string]interface{}{"protocolVersion": "2024-11-05"}
+	case "tools/list":
+		reply.Result = map[string]interface{}{"tools": []Tool{
+			{Name: "search", InputSchema: map[string]interface{}{"type": "object"}},
+			{Name: "write", InputSchema: map[string]interface{}{"type": "object"}},
+		}}
+	case "tools/call":
+		if conn.index == 0 {
+			s.held <- tool
+			return
+		}
+		reply.Result = ToolCallResult{Content: []Content{{Type: "text", Text: tool + " ok"}}}
+	default:
+		reply.Error = &MCPError{Code: -32601, Message: "Method not found: " + msg.Method}
+	}
+	data, _ := json.Marshal(reply)
+	conn.recv <- data
+}
+
+// fakeConn 内存中的 Transport
+type fakeConn struct {
+	server    *fakeServer
+	index     int
+	recv      chan []byte
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+func (f *fakeConn) Send(data []byte) error {
+	select {
+	case <-f.closed:
+		return io.ErrClosedPipe
+	default:
+	}
+	var msg MCPMessage
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return err
+	}
+	f.server.handle(f, msg)
+	return nil
+}
+
+func (f *fakeConn) Receive() ([]byte, error) {
+	select {
+	case data := <-f.recv:
+		return data, nil
+	case <-f.closed:
+		return nil, io.EOF
+	}
+}
+
+func (f *fakeConn) Close() error {
+	f.closeOnce.Do(func() { close(f.closed) })
+	return nil
+}
+
+func TestReconnectReplaysAllowListedTools(t *testing.T) {
+	server := newFakeServer()
+	client, err := NewMCPClient(server.dial, 5*time.Second, &ReconnectConfig{
+		Enabled:        true,
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     10 * time.Millisecond,
+		InflightPolicy: InflightReplay,
+		ReplayTools:    []string{"search"},
+	}, nil)
+	if err != nil {
+		t.Fatalf("NewMCPClient() error = %v", err)
+	}
+	defer client.Close()
+
+	ctx := context.Background()
+	if err := client.Initialize(ctx); err != nil {
+		t.Fatalf("Initialize() error = %v", err)
+	}
+
+	type outcome struct {
+		result *ToolCallResult
+		err    error
+	}
+	results := make(map[string]chan outcome)
+	for _, tool := range []string{"search", "write"} {
+		ch := make(chan outcome, 1)
+		results[tool] = ch
+		go func(tool string) {
+			result, err := client.CallTool(ctx, tool, map[string]interface{}{})
+			ch <- outcome{result, err}
+		}(tool)
+	}
+
+	// 两个调用都已到达服务端后断开连接
+	for i := 0; i < 2; i++ {
+		select {
+		case <-server.held:
+		case <-time.After(2 * time.Second):
+			t.Fatal("工具调用未到达服务端")
+		}
+	}
+	server.drop(0)
+
+	select {
+	case got := <-results["write"]:
+		if !errors.Is(got.err, ErrConnectionLost) {
+			t.Errorf("write: error = %v, want ErrConnectionLost", got.err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("未在白名单中的调用没有失败")
+	}
+
+	select {
+	case got := <-results["search"]:
+		if got.err != nil {
+			t.Fatalf("search: error = %v", got.err)
+		}
+		if len(got.result.Content) != 1 || got.result.Content[0].Text != "search ok" {
+			t.Errorf("search: result = %+v", got.result)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("白名单中的调用没有在重连后重放")
+	}
+
+	if n := server.count(1, "initialize"); n != 1 {
+		t.Errorf("重连后 initialize 次数 = %d, want 1", n)
+	}
+	if n := server.count(1, "notifications/initialized"); n != 1 {
+		t.Errorf("重连后 notifications/initialized 次数 = %d, want 1", n)
+	}
+	if n := server.count(1, "tools/call"); n != 1 {
+		t.Errorf("重连后重放的 tools/call 次数 = %d, want 1", n)
+	}
+	if state := client.State(); state != StateConnected {
+		t.Errorf("State() = %v, want connected", state)
+	}
+}
+
+func TestReconnectFailPolicy(t *testing.T) {
+	server := newFakeServer()
+	client, err := NewMCPClient(server.dial, 5*time.Second, &ReconnectConfig{
+		Enabled:        true,
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     10 * time.Millisecond,
+		ReplayTools:    []string{"search"},
+	}, nil)
+	if err != nil {
+		t.Fatalf("NewMCPClient() error = %v", err)
+	}
+	defer client.Close()
+
+	ctx := context.Background()
+	if err := client.Initialize(ctx); err != nil {
+		t.Fatalf("Initialize() error = %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := client.CallTool(ctx, "search", map[string]interface{}{})
+		done <- err
+	}()
+	<-server.held
+	server.drop(0)
+
+	if err := <-done; !errors.Is(err, ErrConnectionLost) {
+		t.Fatalf("fail 策略下 error = %v, want ErrConnectionLost", err)
+	}
+
+	// 重连完成后新的调用走新连接
+	result, err := client.CallTool(ctx, "search", map[string]interface{}{})
+	if err != nil {
+		t.Fatalf("重连后调用 error = %v", err)
+	}
+	if result.Content[0].Text != "search ok" {
+		t.Errorf("重连后调用 result = %+v", result)
+	}
+	if n := server.count(1, "notifications/initialized"); n != 1 {
+		t.Errorf("重连后 notifications/initialized 次数 = %d, want 1", n)
+	}
+}
+
+func TestReconnectConfigReplayable(t *testing.T) {
+	replay := ReconnectConfig{Enabled: true, InflightPolicy: InflightReplay, ReplayTools: []string{"search"}}
+	call := func(tool string) MCPMessage {
+		return MCPMessage{Method: "tools/call", Params: map[string]interface{}{"name": tool}}
+	}
+	tests := []struct {
+		name string
+		rc   ReconnectConfig
+		msg  MCPMessage
+		want bool
+	}{
+		{"白名单工具", replay, call("search"), true},
+		{"非白名单工具", replay, call("write"), false},
+		{"无参数的工具调用", replay, MCPMessage{Method: "tools/call"}, false},
+		{"工具列表", replay, MCPMessage{Method: "tools/list"}, true},
+		{"fail策略", ReconnectConfig{Enabled: true, InflightPolicy: InflightFail, ReplayTools: []string{"search"}}, call("search"), false},
+		{"未启用重连", ReconnectConfig{InflightPolicy: InflightReplay, ReplayTools: []string{"search"}}, call("search"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.rc.replayable(tt.msg); got != tt.want {
+				t.Errorf("replayable() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReconnectConfigBackoff(t *testing.T) {
+	rc := ReconnectConfig{InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second}.withDefaults()
+	tests := []struct {
+		attempt int
+		max     time.Duration
+	}{
+		{0, 100 * time.Millisecond},
+		{1, 200 * time.Millisecond},
+		{3, 800 * time.Millisecond},
+		{4, time.Second},
+		{50, time.Second},
+	}
+	for _, tt := range tests {
+		for i := 0; i < 20; i++ {
+			if got := rc.backoff(tt.attempt); got < tt.max/2 || got > tt.max {
+				t.Errorf("backoff(%d) = %v, want [%v, %v]", tt.attempt, got, tt.max/2, tt.max)
+			}
+		}
+	}
+}
@@ -0,0 +1,399 @@
+package mcp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// FieldError 单个参数的校验错误
+type FieldError struct {
+	Field   string `json:"field"`   // 参数路径，如 opt.x、tags[0]；根对象为空
+	Rule    string `json:"rule"`    // 违反的约束：required、type、enum、minimum 等
+	Message string `json:"message"` // 可读的错误说明
+}
+
+// ValidationError 工具参数未通过 inputSchema 校验
+type ValidationError struct {
+	Tool   string       `json:"tool"`
+	Errors []FieldError `json:"errors"`
+}
+
+// Error 实现 error 接口
+func (e *ValidationError) Error() string {
+	parts := make([]string, 0, len(e.Errors))
+	for _, fe := range e.Errors {
+		if fe.Field == "" {
+			parts = append(parts, fe.Message)
+		} else {
+			parts = append(parts, fe.Field+": "+fe.Message)
+		}
+	}
+	return fmt.Sprintf("工具 %s 参数校验失败: %s", e.Tool, strings.Join(parts, "; "))
+}
+
+// ValidateArguments 按 JSON Schema 校验工具参数，返回全部字段错误
+// 支持 type、required、enum、const、数值范围、字符串长度与 pattern、
+// 数组长度与 items、嵌套 properties 以及 additionalProperties
+func ValidateArguments(schema map[string]interface{}, args map[string]interface{}) []FieldError {
+	if len(schema) == 0 {
+		return nil
+	}
+
+	// 先经过一次JSON往返，将 []string、结构体等Go类型统一为JSON值
+	normalized, err := normalizeJSON(args)
+	if err != nil {
+		return []FieldError{{Rule: "type", Message: fmt.Sprintf("参数无法序列化为JSON: %v", err)}}
+	}
+
+	var v schemaValidator
+	v.validate("", schema, normalized)
+	return v.errors
+}
+
+// normalizeJSON 将任意值转换为 encoding/json 解码得到的通用表示（数字保留为 json.Number）
+func normalizeJSON(value interface{}) (interface{}, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var out interface{}
+	if err := decoder.Decode(&out); err != nil {
+		return nil, err
+	}
+	if out == nil {
+		// nil map 按空对象处理
+		out = map[string]interface{}{}
+	}
+	return out, nil
+}
+
+// schemaValidator 收集校验过程中的错误
+type schemaValidator struct {
+	errors []FieldError
+}
+
+// addError 记录一个字段错误
+func (v *schemaValidator) addError(path, rule, format string, a ...interface{}) {
+	v.errors = append(v.errors, FieldError{Field: path, Rule: rule, Message: fmt.Sprintf(format, a...)})
+}
+
+// validate 校验单个值
+func (v *schemaValidator) validate(path string, schema map[string]interface{}, value interface{}) {
+	if types := schemaTypes(schema); len(types) > 0 {
+		matched := false
+		for _, t := range types {
+			if matchesType(t, value) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			v.addError(path, "type", "类型应为 %s，实际为 %s", strings.Join(types, "|"), jsonTypeOf(value))
+			return
+		}
+	}
+
+	if enum, ok := schema["enum"].([]interface{}); ok {
+		found := false
+		for _, candidate := range enum {
+			if jsonEqual(candidate, value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			v.addError(path, "enum", "取值必须是 %s 之一", formatEnum(enum))
+		}
+	}
+	if constant, ok := schema["const"]; ok && !jsonEqual(constant, value) {
+		v.addError(path, "const", "取值必须为 %v", constant)
+	}
+
+	switch val := value.(type) {
+	case string:
+		v.validateString(path, schema, val)
+	case map[string]interface{}:
+		v.validateObject(path, schema, val)
+	case []interface{}:
+		v.validateArray(path, schema, val)
+	default:
+		if n, ok := toFloat(value); ok {
+			v.validateNumber(path, schema, n)
+		}
+	}
+}
+
+// validateNumber 校验数值范围
+func (v *schemaValidator) validateNumber(path string, schema map[string]interface{}, n float64) {
+	if min, ok := schemaNumber(schema, "minimum"); ok && n < min {
+		v.addError(path, "minimum", "不能小于 %v", min)
+	}
+	if max, ok := schemaNumber(schema, "maximum"); ok && n > max {
+		v.addError(path, "maximum", "不能大于 %v", max)
+	}
+	if min, ok := schemaNumber(schema, "exclusiveMinimum"); ok && n <= min {
+		v.addError(path, "exclusiveMinimum", "必须大于 %v", min)
+	}
+	if max, ok := schemaNumber(schema, "exclusiveMaximum"); ok && n >= max {
+		v.addError(path, "exclusiveMaximum", "必须小于 %v", max)
+	}
+	if m, ok := schemaNumber(schema, "multipleOf"); ok && m > 0 {
+		if q := n / m; math.Abs(q-math.Round(q)) > 1e-9 {
+			v.addError(path, "multipleOf", "必须是 %v 的倍数", m)
+		}
+	}
+}
+
+// validateString 校验字符串长度和格式
+func (v *schemaValidator) validateString(path string, schema map[string]interface{}, s string) {
+	length := utf8.RuneCountInString(s)
+	if min, ok := schemaNumber(schema, "minLength"); ok && float64(length) < min {
+		v.addError(path, "minLength", "长度不能小于 %v", min)
+	}
+	if max, ok := schemaNumber(schema, "maxLength"); ok && float64(length) > max {
+		v.addError(path, "maxLength", "长度不能大于 %v", max)
+	}
+	if pattern, ok := schema["pattern"].(string); ok {
+		re, err := regexp.Compile(pattern)
+		if err == nil && !re.MatchString(s) {
+			v.addError(path, "pattern", "必须匹配 %s", pattern)
+		}
+	}
+}
+
+// validateObject 校验必填字段、已知属性和额外属性
+func (v *schemaValidator) validateObject(path string, schema map[string]interface{}, obj map[string]interface{}) {
+	if required, ok := schema["required"].([]interface{}); ok {
+		for _, r := range required {
+			name, ok := r.(string)
+			if !ok {
+				continue
+			}
+			if _, exists := obj[name]; !exists {
+				v.addError(joinPath(path, name), "required", "缺少必填参数")
+			}
+		}
+	}
+
+	properties, _ := schema["properties"].(map[string]interface{})
+
+	// 按键名排序，保证错误顺序稳定
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		childPath := joinPath(path, k)
+		if propSchema, ok := properties[k].(map[string]interface{}); ok {
+			v.validate(childPath, propSchema, obj[k])
+			continue
+		}
+		switch additional := schema["additionalProperties"].(type) {
+		case bool:
+			if !additional {
+				v.addError(childPath, "additionalProperties", "不支持的参数")
+			}
+		case map[string]interface{}:
+			v.validate(childPath, additional, obj[k])
+		}
+	}
+}
+
+// validateArray 校验数组长度和元素
+func (v *schemaValidator) validateArray(path string, schema map[string]interface{}, arr []interface{}) {
+	if min, ok := schemaNumber(schema, "minItems"); ok && float64(len(arr)) < min {
+		v.addError(path, "minItems", "元素个数不能少于 %v", min)
+	}
+	if max, ok := schemaNumber(schema, "maxItems"); ok && float64(len(arr)) > max {
+		v.addError(path, "maxItems", "元素个数不能多于 %v", max)
+	}
+	if unique, _ := schema["uniqueItems"].(bool); unique {
+		for i := 0; i < len(arr); i++ {
+			for j := i + 1; j < len(arr); j++ {
+				if jsonEqual(arr[i], arr[j]) {
+					v.addError(fmt.Sprintf("%s[%d]", path, j), "uniqueItems", "与第 %d 个元素重复", i)
+				}
+			}
+		}
+	}
+	if items, ok := schema["items"].(map[string]interface{}); ok {
+		for i, item := range arr {
+			v.validate(fmt.Sprintf("%s[%d]", path, i), items, item)
+		}
+	}
+}
+
+// schemaTypes 读取 type 关键字，兼容字符串和字符串数组两种写法
+func schemaTypes(schema map[string]interface{}) []string {
+	switch t := schema["type"].(type) {
+	case string:
+		return []string{t}
+	case []interface{}:
+		types := make([]string, 0, len(t))
+		for _, item := range t {
+			if s, ok := item.(string); ok {
+				types = append(types, s)
+			}
+		}
+		return types
+	}
+	return nil
+}
+
+// matchesType 判断值是否符合 JSON Schema 类型
+func matchesType(t string, value interface{}) bool {
+	switch t {
+	case "null":
+		return value == nil
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "object":
+		_, ok := value.(map[string]interface{})
+		return ok
+	case "array":
+		_, ok := value.([]interface{})
+		return ok
+	case "number":
+		_, ok := toFloat(value)
+		return ok
+	case "integer":
+		n, ok := toFloat(value)
+		return ok && n == math.Trunc(n)
+	default:
+		// 未知类型不做限制
+		return true
+	}
+}
+
+// jsonTypeOf 返回值对应的 JSON 类型名
+func jsonTypeOf(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	}
+	if n, ok := toFloat(value); ok {
+		if n == math.Trunc(n) {
+			return "integer"
+		}
+		return "number"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+// toFloat 将各种数值表示（含 json.Number）转换为 float64
+func toFloat(value interface{}) (float64, bool) {
+	switch n := value.(type) {
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int8:
+		return float64(n), true
+	case int16:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint:
+		return float64(n), true
+	case uint8:
+		return float64(n), true
+	case uint16:
+		return float64(n), true
+	case uint32:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	}
+	return 0, false
+}
+
+// schemaNumber 读取 schema 中的数值约束
+func schemaNumber(schema map[string]interface{}, key string) (float64, bool) {
+	value, exists := schema[key]
+	if !exists {
+		return 0, false
+	}
+	return toFloat(value)
+}
+
+// jsonEqual 按 JSON 语义比较两个值，对象和数组逐层比较，数值不区分表示方式（json.Number、float64、int 等）
+func jsonEqual(a, b interface{}) bool {
+	if fa, ok := toFloat(a); ok {
+		fb, ok := toFloat(b)
+		return ok && fa == fb
+	}
+	switch va := a.(type) {
+	case map[string]interface{}:
+		vb, ok := b.(map[string]interface{})
+		if !ok || len(va) != len(vb) {
+			return false
+		}
+		for key, x := range va {
+			y, exists := vb[key]
+			if !exists || !jsonEqual(x, y) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		vb, ok := b.([]interface{})
+		if !ok || len(va) != len(vb) {
+			return false
+		}
+		for i := range va {
+			if !jsonEqual(va[i], vb[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// formatEnum 格式化枚举值列表
+func formatEnum(enum []interface{}) string {
+	parts := make([]string, 0, len(enum))
+	for _, e := range enum {
+		b, _ := json.Marshal(e)
+		parts = append(parts, string(b))
+	}
+	return "[" + strings.Join(parts, ", ") + "]"
+}
+
+// joinPath 拼接参数路径
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
@@ -0,0 +1,190 @@
+package mcp
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+// mustSchema 解析测试用的 JSON Schema，数字与工具目录一致解码为 float64
+func mustSchema(t *testing.T, s string) map[string]interface{} {
+	t.Helper()
+	var schema map[string]interface{}
+	if err := json.Unmarshal([]byte(s), &schema); err != nil {
+		t.Fatalf("解析schema失败: %v", err)
+	}
+	return schema
+}
+
+// fieldRule 字段路径和违反的约束，用于比较校验结果
+type fieldRule struct {
+	Field string
+	Rule  string
+}
+
+func TestValidateArguments(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema string
+		args   map[string]interface{}
+		want   []fieldRule
+	}{
+		{
+			name:   "空schema不校验",
+			schema: `{}`,
+			args:   map[string]interface{}{"x": 1},
+		},
+		{
+			name:   "缺少必填参数",
+			schema: `{"type":"object","required":["text","lang"],"properties":{"text":{"type":"string"}}}`,
+			args:   map[string]interface{}{"text": "hi"},
+			want:   []fieldRule{{"lang", "required"}},
+		},
+		{
+			name:   "nil参数按空对象处理",
+			schema: `{"type":"object","required":["text"]}`,
+			args:   nil,
+			want:   []fieldRule{{"text", "required"}},
+		},
+		{
+			name:   "类型不符",
+			schema: `{"type":"object","properties":{"count":{"type":"integer"},"name":{"type":"string"}}}`,
+			args:   map[string]interface{}{"count": 1.5, "name": 3},
+			want:   []fieldRule{{"count", "type"}, {"name", "type"}},
+		},
+		{
+			name:   "整数可以用浮点表示",
+			schema: `{"type":"object","properties":{"count":{"type":"integer"}}}`,
+			args:   map[string]interface{}{"count": 2.0},
+		},
+		{
+			name:   "类型联合",
+			schema: `{"type":"object","properties":{"v":{"type":["string","null"]}}}`,
+			args:   map[string]interface{}{"v": nil},
+		},
+		{
+			name:   "类型联合不匹配",
+			schema: `{"type":"object","properties":{"v":{"type":["string","null"]}}}`,
+			args:   map[string]interface{}{"v": true},
+			want:   []fieldRule{{"v", "type"}},
+		},
+		{
+			name:   "字符串枚举",
+			schema: `{"type":"object","properties":{"mode":{"enum":["plan","execute"]}}}`,
+			args:   map[string]interface{}{"mode": "run"},
+			want:   []fieldRule{{"mode", "enum"}},
+		},
+		{
+			name:   "数值枚举不区分表示方式",
+			schema: `{"type":"object","properties":{"level":{"enum":[1,2,3]}}}`,
+			args:   map[string]interface{}{"level": int64(2)},
+		},
+		{
+			name:   "枚举值为含数字的对象",
+			schema: `{"type":"object","properties":{"size":{"enum":[{"w":1,"h":2},{"w":3,"h":4}]}}}`,
+			args:   map[string]interface{}{"size": map[string]interface{}{"w": 3, "h": 4}},
+		},
+		{
+			name:   "枚举值为数字数组",
+			schema: `{"type":"object","properties":{"point":{"enum":[[0,0],[1,2]]}}}`,
+			args:   map[string]interface{}{"point": []int{1, 2}},
+		},
+		{
+			name:   "枚举值为数字数组不匹配",
+			schema: `{"type":"object","properties":{"point":{"enum":[[0,0],[1,2]]}}}`,
+			args:   map[string]interface{}{"point": []int{2, 1}},
+			want:   []fieldRule{{"point", "enum"}},
+		},
+		{
+			name:   "const嵌套对象",
+			schema: `{"type":"object","properties":{"opt":{"const":{"retries":3,"tags":[1.5]}}}}`,
+			args:   map[string]interface{}{"opt": map[string]interface{}{"retries": 3, "tags": []float64{1.5}}},
+		},
+		{
+			name:   "const不匹配",
+			schema: `{"type":"object","properties":{"opt":{"const":{"retries":3}}}}`,
+			args:   map[string]interface{}{"opt": map[string]interface{}{"retries": 3, "extra": true}},
+			want:   []fieldRule{{"opt", "const"}},
+		},
+		{
+			name:   "数值范围",
+			schema: `{"type":"object","properties":{"n":{"type":"number","minimum":1,"maximum":10},"m":{"exclusiveMinimum":0,"multipleOf":0.5}}}`,
+			args:   map[string]interface{}{"n": 11, "m": 0.75},
+			want:   []fieldRule{{"m", "multipleOf"}, {"n", "maximum"}},
+		},
+		{
+			name:   "字符串长度按字符计算",
+			schema: `{"type":"object","properties":{"s":{"type":"string","maxLength":2},"code":{"pattern":"^[A-Z]+$"}}}`,
+			args:   map[string]interface{}{"s": "你好", "code": "abc"},
+			want:   []fieldRule{{"code", "pattern"}},
+		},
+		{
+			name:   "嵌套属性",
+			schema: `{"type":"object","properties":{"opt":{"type":"object","required":["x"],"properties":{"x":{"type":"integer","minimum":0}}}}}`,
+			args:   map[string]interface{}{"opt": map[string]interface{}{"x": -1}},
+			want:   []fieldRule{{"opt.x", "minimum"}},
+		},
+		{
+			name:   "嵌套必填",
+			schema: `{"type":"object","properties":{"opt":{"type":"object","required":["x"]}}}`,
+			args:   map[string]interface{}{"opt": map[string]interface{}{}},
+			want:   []fieldRule{{"opt.x", "required"}},
+		},
+		{
+			name:   "数组元素和长度",
+			schema: `{"type":"object","properties":{"tags":{"type":"array","minItems":1,"maxItems":3,"uniqueItems":true,"items":{"type":"string"}}}}`,
+			args:   map[string]interface{}{"tags": []interface{}{"a", 1, "a"}},
+			want:   []fieldRule{{"tags[2]", "uniqueItems"}, {"tags[1]", "type"}},
+		},
+		{
+			name:   "不允许额外属性",
+			schema: `{"type":"object","properties":{"a":{}},"additionalProperties":false}`,
+			args:   map[string]interface{}{"a": 1, "b": 2},
+			want:   []fieldRule{{"b", "additionalProperties"}},
+		},
+		{
+			name:   "额外属性按schema校验",
+			schema: `{"type":"object","additionalProperties":{"type":"number"}}`,
+			args:   map[string]interface{}{"a": 1, "b": "x"},
+			want:   []fieldRule{{"b", "type"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := ValidateArguments(mustSchema(t, tt.schema), tt.args)
+			var got []fieldRule
+			for _, fe := range errs {
+				got = append(got, fieldRule{fe.Field, fe.Rule})
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ValidateArguments() = %v, want %v", errs, tt.want)
+			}
+		})
+	}
+}
+
+func TestJSONEqual(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b interface{}
+		want bool
+	}{
+		{"数值不同表示", json.Number("2"), 2.0, true},
+		{"数值与字符串", json.Number("2"), "2", false},
+		{"嵌套对象中的数值", map[string]interface{}{"x": json.Number("1")}, map[string]interface{}{"x": 1.0}, true},
+		{"对象键不同", map[string]interface{}{"x": 1.0}, map[string]interface{}{"y": 1.0}, false},
+		{"对象键数不同", map[string]interface{}{"x": 1.0}, map[string]interface{}{"x": 1.0, "y": 2.0}, false},
+		{"数组中的数值", []interface{}{json.Number("1"), json.Number("2.5")}, []interface{}{1.0, 2.5}, true},
+		{"数组顺序不同", []interface{}{1.0, 2.0}, []interface{}{2.0, 1.0}, false},
+		{"对象与数组", map[string]interface{}{}, []interface{}{}, false},
+		{"null", nil, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := jsonEqual(tt.a, tt.b); got != tt.want {
+				t.Errorf("jsonEqual(%v, %v) = %v, want %v", tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName MCP客户端span的 instrumentation 名称
+const tracerName = "mcp-ai-client/internal/mcp"
+
+// span 属性
+const (
+	attrMethod       = attribute.Key("mcp.method.name")
+	attrToolName     = attribute.Key("mcp.tool.name")
+	attrRequestID    = attribute.Key("jsonrpc.request.id")
+	attrArgumentSize = attribute.Key("mcp.tool.arguments.size")
+	attrErrorType    = attribute.Key("error.type")
+)
+
+// startCallSpan 为一次工具调用创建客户端span，参数大小为序列化后的字节数
+func startCallSpan(ctx context.Context, toolName string, arguments map[string]interface{}) (context.Context, trace.Span) {
+	ctx, span := otel.Tracer(tracerName).Start(ctx, "tools/call "+toolName,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrMethod.String("tools/call"), attrToolName.String(toolName)),
+	)
+	if span.IsRecording() {
+		if data, err := json.Marshal(arguments); err == nil {
+			span.SetAttributes(attrArgumentSize.Int(len(data)))
+		}
+	}
+	return ctx, span
+}
+
+// endCallSpan 记录调用结果并结束span
+func endCallSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetAttributes(attrErrorType.String(ClassifyError(err)))
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// injectTraceContext 把 ctx 中的 W3C trace context（traceparent/tracestate）写入请求的 _meta，
+// 服务端可据此延续同一条链路；未启用追踪时不写入
+func injectTraceContext(ctx context.Context, meta map[string]interface{}) {
+	carrier := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+	for key, value := range carrier {
+		meta[key] = value
+	}
+}
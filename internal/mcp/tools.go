@@ -0,0 +1,182 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+// maxToolPages tools/list 分页上限，防止服务端返回循环游标
+const maxToolPages = 100
+
+// Tool 服务端提供的工具描述
+type Tool struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	InputSchema map[string]interface{} `json:"inputSchema"`
+}
+
+// listToolsResult tools/list 单页结果
+type listToolsResult struct {
+	Tools      []Tool `json:"tools"`
+	NextCursor string `json:"nextCursor,omitempty"`
+}
+
+// toolCatalog 工具目录缓存
+// generation 在每次失效时递增，刷新期间若发生失效则不写入过期结果
+type toolCatalog struct {
+	refreshMu  sync.Mutex // 串行化刷新，避免并发重复拉取
+	mu         sync.RWMutex
+	tools      []Tool
+	index      map[string]Tool
+	valid      bool
+	generation uint64
+}
+
+// ListTools 返回服务端工具列表，缓存有效时直接返回缓存
+func (c *MCPClient) ListTools(ctx context.Context) ([]Tool, error) {
+	if tools, ok := c.cachedTools(); ok {
+		return tools, nil
+	}
+	return c.loadTools(ctx)
+}
+
+// GetTool 按名称查找工具，必要时先拉取工具列表
+func (c *MCPClient) GetTool(ctx context.Context, name string) (Tool, bool, error) {
+	if _, err := c.ListTools(ctx); err != nil {
+		return Tool{}, false, err
+	}
+
+	c.catalog.mu.RLock()
+	defer c.catalog.mu.RUnlock()
+	tool, ok := c.catalog.index[name]
+	return tool, ok, nil
+}
+
+// RefreshTools 忽略缓存，重新拉取完整的工具列表
+func (c *MCPClient) RefreshTools(ctx context.Context) ([]Tool, error) {
+	c.invalidateTools()
+	return c.loadTools(ctx)
+}
+
+// loadTools 按游标分页拉取完整的工具列表并写入缓存
+func (c *MCPClient) loadTools(ctx context.Context) ([]Tool, error) {
+	c.catalog.refreshMu.Lock()
+	defer c.catalog.refreshMu.Unlock()
+
+	// 等待期间可能已被其他调用刷新
+	if tools, ok := c.cachedTools(); ok {
+		return tools, nil
+	}
+
+	c.catalog.mu.RLock()
+	generation := c.catalog.generation
+	c.catalog.mu.RUnlock()
+
+	var tools []Tool
+	cursor := ""
+	for page := 0; ; page++ {
+		if page >= maxToolPages {
+			return nil, fmt.Errorf("工具列表分页超过%d页", maxToolPages)
+		}
+
+		result, err := c.listToolsPage(ctx, cursor)
+		if err != nil {
+			return nil, err
+		}
+		tools = append(tools, result.Tools...)
+
+		if result.NextCursor == "" || result.NextCursor == cursor {
+			break
+		}
+		cursor = result.NextCursor
+	}
+
+	index := make(map[string]Tool, len(tools))
+	for _, tool := range tools {
+		index[tool.Name] = tool
+	}
+
+	c.catalog.mu.Lock()
+	if c.catalog.generation == generation {
+		c.catalog.tools = tools
+		c.catalog.index = index
+		c.catalog.valid = true
+	}
+	c.catalog.mu.Unlock()
+
+	c.logger.Info("MCP工具列表已刷新", "count", len(tools))
+	return append([]Tool(nil), tools...), nil
+}
+
+// listToolsPage 发送一次 tools/list 请求
+func (c *MCPClient) listToolsPage(ctx context.Context, cursor string) (*listToolsResult, error) {
+	params := map[string]interface{}{}
+	if cursor != "" {
+		params["cursor"] = cursor
+	}
+
+	msg := MCPMessage{
+		JSONRPC: "2.0",
+		ID:      c.nextID(),
+		Method:  "tools/list",
+		Params:  params,
+	}
+
+	response, err := c.sendMessage(ctx, msg)
+	if err != nil {
+		return nil, fmt.Errorf("获取工具列表失败: %w", err)
+	}
+	if response.Error != nil {
+		return nil, fmt.Errorf("获取工具列表错误: %w", response.Error)
+	}
+
+	resultBytes, err := json.Marshal(response.Result)
+	if err != nil {
+		return nil, fmt.Errorf("序列化结果失败: %v", err)
+	}
+
+	var result listToolsResult
+	if err := json.Unmarshal(resultBytes, &result); err != nil {
+		return nil, fmt.Errorf("解析工具列表失败: %v", err)
+	}
+	return &result, nil
+}
+
+// cachedTools 返回缓存的工具列表副本
+func (c *MCPClient) cachedTools() ([]Tool, bool) {
+	c.catalog.mu.RLock()
+	defer c.catalog.mu.RUnlock()
+	if !c.catalog.valid {
+		return nil, false
+	}
+	return append([]Tool(nil), c.catalog.tools...), true
+}
+
+// CachedTools 返回当前缓存的工具列表，不会发起请求
+func (c *MCPClient) CachedTools() []Tool {
+	tools, _ := c.cachedTools()
+	return tools
+}
+
+// invalidateTools 使工具缓存失效
+func (c *MCPClient) invalidateTools() {
+	c.catalog.mu.Lock()
+	c.catalog.valid = false
+	c.catalog.generation++
+	c.catalog.mu.Unlock()
+}
+
+// onToolsListChanged 处理 notifications/tools/list_changed：失效缓存并在后台重新拉取
+// 由读取协程调用，不能在此同步等待响应
+func (c *MCPClient) onToolsListChanged() {
+	c.invalidateTools()
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+		defer cancel()
+		if _, err := c.loadTools(ctx); err != nil {
+			c.logger.Warn("刷新MCP工具列表失败", "error", err)
+		}
+	}()
+}
@@ -0,0 +1,44 @@
+package mcp
+
+import "context"
+
+// 报文方向
+const (
+	TraceSend    = "send"
+	TraceReceive = "recv"
+)
+
+// TraceFunc 观察连接上收发的原始JSON-RPC报文，在发送方或读取协程中同步调用，不能阻塞
+type TraceFunc func(direction string, data []byte)
+
+// TraceDialer 包装 Dialer，使其建立的每条连接（包括重连）都把收发的报文交给 fn
+func TraceDialer(dialer Dialer, fn TraceFunc) Dialer {
+	return func(ctx context.Context) (Transport, error) {
+		conn, err := dialer(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return &tracedTransport{Transport: conn, trace: fn}, nil
+	}
+}
+
+// tracedTransport 记录报文的 Transport
+type tracedTransport struct {
+	Transport
+	trace TraceFunc
+}
+
+// Send 发送前记录报文
+func (t *tracedTransport) Send(data []byte) error {
+	t.trace(TraceSend, data)
+	return t.Transport.Send(data)
+}
+
+// Receive 收到后记录报文
+func (t *tracedTransport) Receive() ([]byte, error) {
+	data, err := t.Transport.Receive()
+	if err == nil {
+		t.trace(TraceReceive, data)
+	}
+	return data, err
+}
@@ -0,0 +1,66 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+)
+
+// 支持的传输方式
+const (
+	TransportWebSocket = "websocket"
+	TransportStdio     = "stdio"
+	TransportHTTP      = "http" // Streamable HTTP（POST + SSE）
+)
+
+// Transport MCP消息传输层
+// 每个 Transport 对应一条会话连接，MCPClient 保证同一时刻只有一个读取协程调用 Receive
+type Transport interface {
+	// Send 发送一条完整的JSON-RPC消息，实现需支持并发调用
+	Send(data []byte) error
+	// Receive 阻塞读取下一条完整的JSON-RPC消息，连接断开时返回错误
+	Receive() ([]byte, error)
+	// Close 关闭连接并释放资源
+	Close() error
+}
+
+// Dialer 建立一条新的传输连接，首次连接和断线重连都会调用
+type Dialer func(ctx context.Context) (Transport, error)
+
+// TransportConfig 传输方式配置
+type TransportConfig struct {
+	Type      string       // websocket（默认）、stdio 或 http
+	ServerURL string       // websocket 或 Streamable HTTP 服务地址
+	Stdio     StdioConfig  // stdio 子进程配置
+	HTTP      HTTPConfig   // Streamable HTTP 配置
+	Logger    *slog.Logger // 传输层日志（子进程stderr、SSE续传等），为 nil 时使用 slog.Default()
+}
+
+// NewDialer 根据配置创建对应传输方式的 Dialer
+func NewDialer(config TransportConfig) (Dialer, error) {
+	switch config.Type {
+	case "", TransportWebSocket:
+		if config.ServerURL == "" {
+			return nil, fmt.Errorf("websocket传输需要配置server_url")
+		}
+		return func(ctx context.Context) (Transport, error) {
+			return DialWebSocket(ctx, config.ServerURL)
+		}, nil
+	case TransportStdio:
+		if config.Stdio.Command == "" {
+			return nil, fmt.Errorf("stdio传输需要配置command")
+		}
+		return func(ctx context.Context) (Transport, error) {
+			return StartStdio(ctx, config.Stdio, config.Logger)
+		}, nil
+	case TransportHTTP:
+		if config.ServerURL == "" {
+			return nil, fmt.Errorf("http传输需要配置server_url")
+		}
+		return func(ctx context.Context) (Transport, error) {
+			return DialHTTP(ctx, config.ServerURL, config.HTTP, config.Logger)
+		}, nil
+	default:
+		return nil, fmt.Errorf("不支持的MCP传输方式: %s", config.Type)
+	}
+}
@@ -0,0 +1,479 @@
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mcp-ai-client/internal/logging"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// sessionHeader Streamable HTTP 会话ID请求/响应头
+	sessionHeader = "Mcp-Session-Id"
+	// lastEventIDHeader SSE断点续传请求头
+	lastEventIDHeader = "Last-Event-ID"
+	// sseDefaultRetry 服务端未指定 retry 时的SSE重连间隔
+	sseDefaultRetry = time.Second
+	// sseMaxFailures SSE流连续失败次数上限，超过后认为连接断开
+	sseMaxFailures = 5
+)
+
+// HTTPConfig Streamable HTTP 传输配置
+type HTTPConfig struct {
+	Headers map[string]string `yaml:"headers"` // 每个请求附带的额外请求头，例如鉴权
+}
+
+// httpTransport MCP Streamable HTTP 传输
+// 客户端消息通过 POST 发送，响应以 JSON 或 SSE 流返回；
+// 会话建立后额外打开一个 GET SSE 流接收服务端主动推送的消息，断开后按 Last-Event-ID 续传
+type httpTransport struct {
+	endpoint string
+	headers  map[string]string
+	client   *http.Client
+	logger   *slog.Logger
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	incoming chan []byte
+	done     chan struct{}
+	failOnce sync.Once
+	err      error
+
+	mu            sync.Mutex
+	sessionID     string
+	listenStarted bool
+	retry         time.Duration                 // 服务端通过 SSE retry 字段指定的重连间隔
+	inflight      map[string]context.CancelFunc // 等待响应的 POST，按请求ID（JSON原文）索引
+}
+
+// DialHTTP 创建 Streamable HTTP 传输
+// HTTP 无需预先建连，会话在第一次 POST（initialize）时由服务端分配
+func DialHTTP(ctx context.Context, endpoint string, config HTTPConfig, logger *slog.Logger) (Transport, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	tctx, cancel := context.WithCancel(context.Background())
+	return &httpTransport{
+		endpoint: endpoint,
+		headers:  config.Headers,
+		client:   &http.Client{},
+		logger:   logging.OrDefault(logger),
+		ctx:      tctx,
+		cancel:   cancel,
+		incoming: make(chan []byte, 64),
+		done:     make(chan struct{}),
+		inflight: make(map[string]context.CancelFunc),
+	}, nil
+}
+
+// Send POST 一条消息
+// 请求在后台协程中等待响应，Send 在发出后立即返回，响应（包括非2xx时的错误）经 Receive 投递，
+// 调用方因此能按自身超时放弃请求；通知和对服务端请求的回复按规范只会得到 202，同步发送以保持顺序
+// 发送 notifications/cancelled 后同时中止被取消请求的 POST
+func (t *httpTransport) Send(data []byte) error {
+	var msg outgoingMessage
+	if err := json.Unmarshal(data, &msg); err != nil || len(msg.ID) == 0 || msg.Method == "" {
+		err := t.post(t.ctx, data, nil)
+		if err == nil && msg.Method == "notifications/cancelled" {
+			t.abort(msg.Params.RequestID)
+		}
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(t.ctx)
+	key := string(msg.ID)
+	t.mu.Lock()
+	t.inflight[key] = cancel
+	t.mu.Unlock()
+	go func() {
+		defer func() {
+			t.mu.Lock()
+			delete(t.inflight, key)
+			t.mu.Unlock()
+			cancel()
+		}()
+		if err := t.post(ctx, data, msg.ID); err != nil && ctx.Err() == nil {
+			t.logger.Warn("MCP请求失败", "rpc_id", key, "error", err)
+		}
+	}()
+	return nil
+}
+
+// outgoingMessage Send 需要识别的出站消息字段
+type outgoingMessage struct {
+	ID     json.RawMessage `json:"id"`
+	Method string          `json:"method"`
+	Params struct {
+		RequestID json.RawMessage `json:"requestId"`
+	} `json:"params"`
+}
+
+// abort 中止指定请求ID仍在等待响应的 POST
+func (t *httpTransport) abort(id json.RawMessage) {
+	if len(id) == 0 {
+		return
+	}
+	t.mu.Lock()
+	cancel := t.inflight[string(id)]
+	t.mu.Unlock()
+	if cancel != nil {
+		cancel()
+	}
+}
+
+// post 发送一条消息并处理响应，id 为请求ID，通知和回复为 nil
+// 非2xx响应时，请求会收到服务端返回的 JSON-RPC 错误（响应体不是 JSON-RPC 错误时由状态码构造）
+func (t *httpTransport) post(ctx context.Context, data []byte, id json.RawMessage) error {
+	req, err := t.newRequest(ctx, http.MethodPost, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json, text/event-stream")
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		if ctx.Err() != nil && t.ctx.Err() == nil {
+			return ctx.Err() // 请求已被取消，连接本身正常
+		}
+		err = fmt.Errorf("POST MCP消息失败: %v", err)
+		t.fail(err)
+		return err
+	}
+
+	if sessionID := resp.Header.Get(sessionHeader); sessionID != "" {
+		t.mu.Lock()
+		t.sessionID = sessionID
+		t.mu.Unlock()
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusAccepted:
+		resp.Body.Close()
+	case resp.StatusCode == http.StatusNotFound && t.currentSession() != "":
+		// 会话已失效：让读取协程感知断线，由重连流程重新初始化
+		resp.Body.Close()
+		err := fmt.Errorf("MCP会话已失效")
+		t.fail(err)
+		return err
+	case resp.StatusCode < 200 || resp.StatusCode >= 300:
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+		resp.Body.Close()
+		err := fmt.Errorf("MCP服务器返回 %s: %s", resp.Status, strings.TrimSpace(string(body)))
+		if id != nil {
+			// 错误交给等待该请求的调用方
+			t.deliver(errorResponse(id, body, err))
+			return nil
+		}
+		return err
+	default:
+		if err := t.handleResponseBody(ctx, resp); err != nil {
+			return err
+		}
+	}
+
+	t.startListener()
+	return nil
+}
+
+// errorResponse 为请求 id 构造 JSON-RPC 错误响应：优先使用响应体中的 error 对象，
+// 响应体不是 JSON-RPC 错误时以 CodeInternalError 和 fallback 的信息代替
+func errorResponse(id json.RawMessage, body []byte, fallback error) []byte {
+	var rpc struct {
+		Error json.RawMessage `json:"error"`
+	}
+	var errObj json.RawMessage
+	if json.Unmarshal(body, &rpc) == nil && len(rpc.Error) > 0 && string(rpc.Error) != "null" {
+		errObj = rpc.Error
+	} else {
+		errObj, _ = json.Marshal(MCPError{Code: CodeInternalError, Message: fallback.Error()})
+	}
+	data, _ := json.Marshal(struct {
+		JSONRPC string          `json:"jsonrpc"`
+		ID      json.RawMessage `json:"id"`
+		Error   json.RawMessage `json:"error"`
+	}{"2.0", id, errObj})
+	return data
+}
+
+// handleResponseBody 处理 POST 的 200 响应：JSON 直接投递，SSE 读取到流结束（中断时续传，请求被取消时不续传）
+func (t *httpTransport) handleResponseBody(ctx context.Context, resp *http.Response) error {
+	defer resp.Body.Close()
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	switch mediaType {
+	case "text/event-stream":
+		lastEventID, err := t.consumeSSE(resp.Body, "")
+		if err != nil && lastEventID != "" && ctx.Err() == nil {
+			// 响应流中断：通过 GET + Last-Event-ID 续传剩余消息
+			t.logger.Warn("MCP响应流中断，尝试续传", "last_event_id", lastEventID, "error", err)
+			t.resumeStream(lastEventID)
+		}
+		return nil
+	default:
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return fmt.Errorf("读取MCP响应失败: %v", err)
+		}
+		return t.deliverJSON(body)
+	}
+}
+
+// deliverJSON 投递 JSON 响应体，兼容批量响应数组
+func (t *httpTransport) deliverJSON(body []byte) error {
+	body = bytes.TrimSpace(body)
+	if len(body) == 0 {
+		return nil
+	}
+	if body[0] != '[' {
+		t.deliver(body)
+		return nil
+	}
+
+	var batch []json.RawMessage
+	if err := json.Unmarshal(body, &batch); err != nil {
+		return fmt.Errorf("解析批量响应失败: %v", err)
+	}
+	for _, msg := range batch {
+		t.deliver(msg)
+	}
+	return nil
+}
+
+// deliver 将一条消息交给 Receive
+func (t *httpTransport) deliver(msg []byte) {
+	select {
+	case t.incoming <- msg:
+	case <-t.done:
+	}
+}
+
+// startListener 会话建立后打开 GET SSE 流，仅启动一次
+func (t *httpTransport) startListener() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.listenStarted {
+		return
+	}
+	t.listenStarted = true
+	go t.listen()
+}
+
+// listen 维持服务端推送流，断开后携带 Last-Event-ID 重连
+func (t *httpTransport) listen() {
+	lastEventID := ""
+	failures := 0
+	for t.ctx.Err() == nil {
+		resp, err := t.openStream(lastEventID)
+		if errors.Is(err, errStreamUnsupported) {
+			t.logger.Info("MCP服务器不支持GET推送流，仅使用POST响应")
+			return
+		}
+		if err == nil {
+			lastEventID, err = t.consumeSSE(resp.Body, lastEventID)
+			resp.Body.Close()
+		}
+		if t.ctx.Err() != nil {
+			return
+		}
+
+		// 服务端正常结束流时直接重连；连续出错才认为连接断开
+		if err == nil {
+			failures = 0
+		} else if failures++; failures >= sseMaxFailures {
+			t.fail(fmt.Errorf("MCP推送流连续失败%d次: %v", failures, err))
+			return
+		}
+		select {
+		case <-time.After(t.retryInterval()):
+		case <-t.ctx.Done():
+			return
+		}
+	}
+}
+
+// resumeStream 通过 GET + Last-Event-ID 续传被中断的响应流，直到流正常结束
+func (t *httpTransport) resumeStream(lastEventID string) {
+	for failures := 0; failures < sseMaxFailures && t.ctx.Err() == nil; failures++ {
+		resp, err := t.openStream(lastEventID)
+		if errors.Is(err, errStreamUnsupported) {
+			t.logger.Warn("MCP服务器不支持续传，响应流消息可能丢失")
+			return
+		}
+		if err == nil {
+			lastEventID, err = t.consumeSSE(resp.Body, lastEventID)
+			resp.Body.Close()
+			if err == nil {
+				return
+			}
+		}
+		t.logger.Warn("MCP响应流续传失败", "last_event_id", lastEventID, "error", err)
+		select {
+		case <-time.After(t.retryInterval()):
+		case <-t.ctx.Done():
+			return
+		}
+	}
+}
+
+// errStreamUnsupported 服务端不提供 GET SSE 流（405）
+var errStreamUnsupported = errors.New("MCP服务器不支持SSE流")
+
+// openStream 发起 GET SSE 请求
+func (t *httpTransport) openStream(lastEventID string) (*http.Response, error) {
+	req, err := t.newRequest(t.ctx, http.MethodGet, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	if lastEventID != "" {
+		req.Header.Set(lastEventIDHeader, lastEventID)
+	}
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("打开MCP推送流失败: %v", err)
+	}
+	if resp.StatusCode == http.StatusMethodNotAllowed {
+		resp.Body.Close()
+		return nil, errStreamUnsupported
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("打开MCP推送流失败: %s", resp.Status)
+	}
+	return resp, nil
+}
+
+// consumeSSE 读取SSE事件并投递 message 事件的数据，返回最后收到的事件ID
+// 流正常结束返回 nil 错误
+func (t *httpTransport) consumeSSE(body io.Reader, lastEventID string) (string, error) {
+	reader := bufio.NewReader(body)
+	var data []string
+	event := ""
+
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			if err == io.EOF {
+				return lastEventID, nil
+			}
+			return lastEventID, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+
+		// 空行表示一个事件结束
+		if line == "" {
+			if len(data) > 0 && (event == "" || event == "message") {
+				t.deliver([]byte(strings.Join(data, "\n")))
+			}
+			data = data[:0]
+			event = ""
+			continue
+		}
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "data":
+			data = append(data, value)
+		case "event":
+			event = value
+		case "id":
+			lastEventID = value
+		case "retry":
+			if ms, err := strconv.Atoi(value); err == nil && ms > 0 {
+				t.mu.Lock()
+				t.retry = time.Duration(ms) * time.Millisecond
+				t.mu.Unlock()
+			}
+		}
+	}
+}
+
+// newRequest 创建附带会话ID和自定义请求头的请求
+func (t *httpTransport) newRequest(ctx context.Context, method string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, t.endpoint, body)
+	if err != nil {
+		return nil, fmt.Errorf("创建MCP请求失败: %v", err)
+	}
+	for k, v := range t.headers {
+		req.Header.Set(k, v)
+	}
+	if id := t.currentSession(); id != "" {
+		req.Header.Set(sessionHeader, id)
+	}
+	return req, nil
+}
+
+// retryInterval 返回SSE重连间隔
+func (t *httpTransport) retryInterval() time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.retry > 0 {
+		return t.retry
+	}
+	return sseDefaultRetry
+}
+
+// currentSession 返回当前会话ID
+func (t *httpTransport) currentSession() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.sessionID
+}
+
+// Receive 返回下一条服务端消息
+func (t *httpTransport) Receive() ([]byte, error) {
+	select {
+	case msg := <-t.incoming:
+		return msg, nil
+	case <-t.done:
+		return nil, t.err
+	}
+}
+
+// fail 标记传输失效，Receive 随后返回该错误
+func (t *httpTransport) fail(err error) {
+	t.failOnce.Do(func() {
+		t.err = err
+		close(t.done)
+		t.cancel()
+	})
+}
+
+// Close 终止会话并停止所有后台流
+func (t *httpTransport) Close() error {
+	if id := t.currentSession(); id != "" && t.ctx.Err() == nil {
+		// 尽力通知服务端释放会话，失败不影响关闭
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		req, err := http.NewRequestWithContext(ctx, http.MethodDelete, t.endpoint, nil)
+		if err == nil {
+			for k, v := range t.headers {
+				req.Header.Set(k, v)
+			}
+			req.Header.Set(sessionHeader, id)
+			if resp, err := t.client.Do(req); err == nil {
+				resp.Body.Close()
+			}
+		}
+		cancel()
+	}
+	t.fail(fmt.Errorf("连接已关闭"))
+	return nil
+}
@@ -0,0 +1,234 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// receiveMessage 在超时时间内读取下一条消息
+func receiveMessage(t *testing.T, tr Transport) MCPMessage {
+	t.Helper()
+	type received struct {
+		data []byte
+		err  error
+	}
+	ch := make(chan received, 1)
+	go func() {
+		data, err := tr.Receive()
+		ch <- received{data, err}
+	}()
+	select {
+	case r := <-ch:
+		if r.err != nil {
+			t.Fatalf("Receive() error = %v", r.err)
+		}
+		var msg MCPMessage
+		if err := json.Unmarshal(r.data, &msg); err != nil {
+			t.Fatalf("解析消息失败: %v\n%s", err, r.data)
+		}
+		return msg
+	case <-time.After(2 * time.Second):
+		t.Fatal("等待消息超时")
+		return MCPMessage{}
+	}
+}
+
+// dialTestHTTP 连接测试服务器，测试结束时关闭
+func dialTestHTTP(t *testing.T, srv *httptest.Server, config HTTPConfig) Transport {
+	t.Helper()
+	tr, err := DialHTTP(context.Background(), srv.URL, config, nil)
+	if err != nil {
+		t.Fatalf("DialHTTP() error = %v", err)
+	}
+	t.Cleanup(func() { tr.Close() })
+	return tr
+}
+
+// writeSSE 写出一个SSE事件并刷新
+func writeSSE(w http.ResponseWriter, id, data string) {
+	if id != "" {
+		fmt.Fprintf(w, "id: %s\n", id)
+	}
+	fmt.Fprintf(w, "data: %s\n\n", data)
+	w.(http.Flusher).Flush()
+}
+
+// rpcID 读取请求体中的JSON-RPC ID
+func rpcID(t *testing.T, r *http.Request) string {
+	var msg struct {
+		ID json.RawMessage `json:"id"`
+	}
+	body, _ := io.ReadAll(r.Body)
+	if err := json.Unmarshal(body, &msg); err != nil {
+		t.Errorf("解析请求体失败: %v", err)
+	}
+	return string(msg.ID)
+}
+
+func TestHTTPTransportJSONResponseAndSession(t *testing.T) {
+	var mu sync.Mutex
+	var sessions []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer test" {
+			t.Errorf("Authorization = %q, want 自定义请求头", got)
+		}
+		mu.Lock()
+		sessions = append(sessions, r.Header.Get(sessionHeader))
+		mu.Unlock()
+
+		id := rpcID(t, r)
+		w.Header().Set(sessionHeader, "sess-1")
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%s,"result":{"ok":true}}`, id)
+	}))
+	defer srv.Close()
+
+	tr := dialTestHTTP(t, srv, HTTPConfig{Headers: map[string]string{"Authorization": "Bearer test"}})
+	for i := 1; i <= 2; i++ {
+		if err := tr.Send([]byte(fmt.Sprintf(`{"jsonrpc":"2.0","id":%d,"method":"ping"}`, i))); err != nil {
+			t.Fatalf("Send() error = %v", err)
+		}
+		msg := receiveMessage(t, tr)
+		if fmt.Sprint(msg.ID) != fmt.Sprint(i) || msg.Result == nil {
+			t.Fatalf("响应 = %+v, want id %d 的结果", msg, i)
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(sessions) != 2 || sessions[0] != "" || sessions[1] != "sess-1" {
+		t.Errorf("请求携带的会话ID = %q, want [\"\" \"sess-1\"]", sessions)
+	}
+}
+
+func TestHTTPTransportSSEResponse(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		id := rpcID(t, r)
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, ": keep-alive\n\n")
+		writeSSE(w, "1", `{"jsonrpc":"2.0","method":"notifications/progress","params":{"progress":1}}`)
+		fmt.Fprint(w, "event: other\ndata: ignored\n\n")
+		// 多行 data 拼接为一条消息
+		fmt.Fprintf(w, "id: 2\ndata: {\"jsonrpc\":\"2.0\",\ndata: \"id\":%s,\"result\":{}}\n\n", id)
+	}))
+	defer srv.Close()
+
+	tr := dialTestHTTP(t, srv, HTTPConfig{})
+	if err := tr.Send([]byte(`{"jsonrpc":"2.0","id":7,"method":"tools/call"}`)); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if msg := receiveMessage(t, tr); msg.Method != "notifications/progress" {
+		t.Errorf("第1条消息 = %+v, want 进度通知", msg)
+	}
+	if msg := receiveMessage(t, tr); fmt.Sprint(msg.ID) != "7" || msg.Result == nil {
+		t.Errorf("第2条消息 = %+v, want id 7 的结果", msg)
+	}
+}
+
+func TestHTTPTransportErrorStatus(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		body     string
+		wantCode int
+		wantMsg  string
+	}{
+		{"响应体不是JSON-RPC错误", http.StatusInternalServerError, "boom", CodeInternalError, "500"},
+		{"响应体是JSON-RPC错误", http.StatusBadRequest, `{"jsonrpc":"2.0","id":1,"error":{"code":-32602,"message":"bad params"}}`, -32602, "bad params"},
+		{"无会话时的404", http.StatusNotFound, "", CodeInternalError, "404"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				fmt.Fprint(w, tt.body)
+			}))
+			defer srv.Close()
+
+			tr := dialTestHTTP(t, srv, HTTPConfig{})
+			if err := tr.Send([]byte(`{"jsonrpc":"2.0","id":1,"method":"tools/call"}`)); err != nil {
+				t.Fatalf("Send() error = %v", err)
+			}
+			msg := receiveMessage(t, tr)
+			if fmt.Sprint(msg.ID) != "1" || msg.Error == nil {
+				t.Fatalf("响应 = %+v, want id 1 的错误", msg)
+			}
+			if msg.Error.Code != tt.wantCode || !strings.Contains(msg.Error.Message, tt.wantMsg) {
+				t.Errorf("错误 = %+v, want code %d 且包含 %q", msg.Error, tt.wantCode, tt.wantMsg)
+			}
+		})
+	}
+}
+
+func TestHTTPTransportNotificationErrorStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer srv.Close()
+
+	tr := dialTestHTTP(t, srv, HTTPConfig{})
+	err := tr.Send([]byte(`{"jsonrpc":"2.0","method":"notifications/initialized"}`))
+	if err == nil || !strings.Contains(err.Error(), "502") {
+		t.Errorf("Send() error = %v, want 502", err)
+	}
+}
+
+func TestHTTPTransportResumeAfterStreamCut(t *testing.T) {
+	resumed := make(chan string, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost:
+			rpcID(t, r)
+			w.Header().Set(sessionHeader, "sess-1")
+			w.Header().Set("Content-Type", "text/event-stream")
+			writeSSE(w, "evt-1", `{"jsonrpc":"2.0","method":"notifications/progress","params":{"progress":1}}`)
+			// 不结束分块编码直接断开连接，模拟响应流中途被切断
+			panic(http.ErrAbortHandler)
+		case r.Method == http.MethodGet && r.Header.Get(lastEventIDHeader) != "":
+			resumed <- r.Header.Get(lastEventIDHeader)
+			if got := r.Header.Get(sessionHeader); got != "sess-1" {
+				t.Errorf("续传请求的会话ID = %q, want sess-1", got)
+			}
+			w.Header().Set("Content-Type", "text/event-stream")
+			writeSSE(w, "evt-2", `{"jsonrpc":"2.0","id":3,"result":{}}`)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}))
+	defer srv.Close()
+
+	tr := dialTestHTTP(t, srv, HTTPConfig{})
+	if err := tr.Send([]byte(`{"jsonrpc":"2.0","id":3,"method":"tools/call"}`)); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if msg := receiveMessage(t, tr); msg.Method != "notifications/progress" {
+		t.Errorf("第1条消息 = %+v, want 进度通知", msg)
+	}
+	if msg := receiveMessage(t, tr); fmt.Sprint(msg.ID) != "3" || msg.Result == nil {
+		t.Errorf("第2条消息 = %+v, want 续传得到的 id 3 的结果", msg)
+	}
+	select {
+	case id := <-resumed:
+		if id != "evt-1" {
+			t.Errorf("Last-Event-ID = %q, want evt-1", id)
+		}
+	default:
+		t.Error("没有通过 Last-Event-ID 续传")
+	}
+}
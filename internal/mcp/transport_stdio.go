@@ -0,0 +1,161 @@
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"mcp-ai-client/internal/logging"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+// stdioStopTimeout 关闭stdin后等待子进程自行退出的时间，超时则强制结束
+const stdioStopTimeout = 3 * time.Second
+
+// stdioExitWait stdout关闭后等待子进程退出状态的时间，用于在错误中报告退出码
+const stdioExitWait = time.Second
+
+// StdioConfig stdio传输配置：启动本地MCP服务器子进程
+type StdioConfig struct {
+	Command string   `yaml:"command"`
+	Args    []string `yaml:"args"`
+	Env     []string `yaml:"env"` // 追加的环境变量，格式 KEY=VALUE
+	Dir     string   `yaml:"dir"` // 子进程工作目录，为空时使用当前目录
+}
+
+// stdioTransport 通过子进程的stdin/stdout收发以换行分隔的JSON-RPC消息
+type stdioTransport struct {
+	cmd        *exec.Cmd
+	stdin      io.WriteCloser
+	stdout     *bufio.Reader
+	stdoutPipe io.Closer
+	logger     *slog.Logger
+
+	writeMu   sync.Mutex
+	closeOnce sync.Once
+	exited    chan struct{} // 子进程退出后关闭
+	waitErr   error         // 子进程退出状态，exited 关闭后可读
+}
+
+// StartStdio 启动子进程并返回基于其stdin/stdout的传输
+// ctx 仅用于启动阶段，子进程生命周期由 Close 控制；子进程的stderr逐行写入 logger
+func StartStdio(ctx context.Context, config StdioConfig, logger *slog.Logger) (Transport, error) {
+	logger = logging.OrDefault(logger)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	cmd := exec.Command(config.Command, config.Args...)
+	cmd.Dir = config.Dir
+	cmd.Env = append(os.Environ(), config.Env...)
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, fmt.Errorf("创建stdin管道失败: %v", err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("创建stdout管道失败: %v", err)
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, fmt.Errorf("创建stderr管道失败: %v", err)
+	}
+
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("启动MCP服务器进程失败: %v", err)
+	}
+	logger = logger.With("pid", cmd.Process.Pid)
+	logger.Info("MCP服务器进程已启动", "command", config.Command)
+
+	t := &stdioTransport{
+		cmd:        cmd,
+		stdin:      stdin,
+		stdout:     bufio.NewReader(stdout),
+		stdoutPipe: stdout,
+		logger:     logger,
+		exited:     make(chan struct{}),
+	}
+
+	go t.logStderr(stderr)
+	// 使用 Process.Wait 而不是 cmd.Wait：后者会在读取完成前关闭stdout管道，丢失进程退出前的最后输出
+	go func() {
+		state, err := cmd.Process.Wait()
+		if err == nil && !state.Success() {
+			err = fmt.Errorf("%s", state.String())
+		}
+		t.waitErr = err
+		t.logger.Info("MCP服务器进程已退出", "error", err)
+		close(t.exited)
+	}()
+
+	return t, nil
+}
+
+// logStderr 将子进程stderr逐行写入日志，子进程退出后关闭管道
+func (t *stdioTransport) logStderr(stderr io.ReadCloser) {
+	defer stderr.Close()
+	scanner := bufio.NewScanner(stderr)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		t.logger.Info("MCP服务器stderr", "line", scanner.Text())
+	}
+}
+
+// Send 写入一行JSON消息；消息本身不能包含换行
+func (t *stdioTransport) Send(data []byte) error {
+	if bytes.ContainsAny(data, "\r\n") {
+		return fmt.Errorf("stdio消息不能包含换行符")
+	}
+
+	t.writeMu.Lock()
+	defer t.writeMu.Unlock()
+	if _, err := t.stdin.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("写入MCP服务器进程失败: %v", err)
+	}
+	return nil
+}
+
+// Receive 读取下一行非空消息
+func (t *stdioTransport) Receive() ([]byte, error) {
+	for {
+		line, err := t.stdout.ReadBytes('\n')
+		line = bytes.TrimSpace(line)
+		if len(line) > 0 {
+			return line, nil
+		}
+		if err != nil {
+			if err == io.EOF {
+				// stdout 关闭通常早于 Wait 返回，稍等退出状态以便报告退出原因
+				select {
+				case <-t.exited:
+					return nil, fmt.Errorf("MCP服务器进程已退出: %v", t.waitErr)
+				case <-time.After(stdioExitWait):
+					return nil, fmt.Errorf("MCP服务器进程输出已关闭")
+				}
+			}
+			return nil, err
+		}
+	}
+}
+
+// Close 关闭stdin通知子进程退出，超时后强制结束
+func (t *stdioTransport) Close() error {
+	t.closeOnce.Do(func() {
+		t.stdin.Close()
+		select {
+		case <-t.exited:
+		case <-time.After(stdioStopTimeout):
+			t.logger.Warn("MCP服务器进程未按时退出，强制结束", "timeout", stdioStopTimeout)
+			t.cmd.Process.Kill()
+			<-t.exited
+		}
+		t.stdoutPipe.Close()
+	})
+	return nil
+}
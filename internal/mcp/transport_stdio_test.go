@@ -0,0 +1,121 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// stdioHelperEnv 非空时测试二进制作为假的MCP服务器子进程运行，取值为服务器行为
+const stdioHelperEnv = "MCP_STDIO_TEST_SERVER"
+
+// TestStdioHelperProcess 不是真正的测试：由 startTestStdio 以子进程方式启动，
+// 从stdin逐行读取请求并按 stdioHelperEnv 指定的行为回复
+//   - reply: 每个请求回复一个结果，先输出空行，并把结果拆成两次写出
+//   - exit: 收到第一个请求后输出不带换行的响应，然后以退出码3退出
+func TestStdioHelperProcess(t *testing.T) {
+	mode := os.Getenv(stdioHelperEnv)
+	if mode == "" {
+		return
+	}
+
+	fmt.Fprintln(os.Stderr, "fake server started")
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		var msg struct {
+			ID json.RawMessage `json:"id"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
+			fmt.Fprintf(os.Stderr, "bad request: %v\n", err)
+			os.Exit(2)
+		}
+		reply := fmt.Sprintf(`{"jsonrpc":"2.0","id":%s,"result":{"echo":%q}}`, msg.ID, scanner.Text())
+
+		switch mode {
+		case "reply":
+			fmt.Fprint(os.Stdout, "\n  \n")
+			half := len(reply) / 2
+			fmt.Fprint(os.Stdout, reply[:half])
+			os.Stdout.Sync()
+			time.Sleep(20 * time.Millisecond)
+			fmt.Fprintln(os.Stdout, reply[half:])
+		case "exit":
+			fmt.Fprint(os.Stdout, reply)
+			os.Exit(3)
+		}
+	}
+	os.Exit(0)
+}
+
+// startTestStdio 通过 NewDialer 启动测试二进制作为stdio服务器，测试结束时关闭
+func startTestStdio(t *testing.T, mode string) Transport {
+	t.Helper()
+	dialer, err := NewDialer(TransportConfig{
+		Type: TransportStdio,
+		Stdio: StdioConfig{
+			Command: os.Args[0],
+			Args:    []string{"-test.run=^TestStdioHelperProcess$"},
+			Env:     []string{stdioHelperEnv + "=" + mode},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewDialer() error = %v", err)
+	}
+	tr, err := dialer(context.Background())
+	if err != nil {
+		t.Fatalf("启动stdio服务器失败: %v", err)
+	}
+	t.Cleanup(func() { tr.Close() })
+	return tr
+}
+
+func TestStdioTransportFraming(t *testing.T) {
+	tr := startTestStdio(t, "reply")
+
+	// 多条请求连续写出，响应按行分帧，空行被跳过，分两次写出的行被完整读取
+	for i := 1; i <= 3; i++ {
+		if err := tr.Send([]byte(fmt.Sprintf(`{"jsonrpc":"2.0","id":%d,"method":"ping"}`, i))); err != nil {
+			t.Fatalf("Send() error = %v", err)
+		}
+	}
+	for i := 1; i <= 3; i++ {
+		msg := receiveMessage(t, tr)
+		if fmt.Sprint(msg.ID) != fmt.Sprint(i) {
+			t.Fatalf("第%d条响应 id = %v", i, msg.ID)
+		}
+		result, _ := msg.Result.(map[string]interface{})
+		if echo, _ := result["echo"].(string); !strings.Contains(echo, `"method":"ping"`) {
+			t.Errorf("第%d条响应 = %+v, want 原样回显请求", i, msg)
+		}
+	}
+
+	if err := tr.Send([]byte("{\"jsonrpc\":\"2.0\",\n\"id\":4}")); err == nil {
+		t.Error("Send() 含换行的消息应返回错误")
+	}
+}
+
+func TestStdioTransportChildExit(t *testing.T) {
+	tr := startTestStdio(t, "exit")
+
+	if err := tr.Send([]byte(`{"jsonrpc":"2.0","id":1,"method":"ping"}`)); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	// 进程退出前输出的最后一行没有换行，仍应作为一条消息读出
+	if msg := receiveMessage(t, tr); fmt.Sprint(msg.ID) != "1" {
+		t.Fatalf("响应 = %+v, want id 1", msg)
+	}
+
+	_, err := tr.Receive()
+	if err == nil || !strings.Contains(err.Error(), "已退出") || !strings.Contains(err.Error(), "exit status 3") {
+		t.Errorf("Receive() error = %v, want 子进程退出码3", err)
+	}
+
+	if err := tr.Close(); err != nil {
+		t.Errorf("Close() error = %v", err)
+	}
+}
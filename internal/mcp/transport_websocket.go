@@ -0,0 +1,43 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/gorilla/websocket"
+)
+
+// websocketTransport 基于WebSocket的传输，每个文本帧是一条JSON-RPC消息
+type websocketTransport struct {
+	conn    *websocket.Conn
+	writeMu sync.Mutex // gorilla/websocket 不支持并发写
+}
+
+// DialWebSocket 连接WebSocket形式的MCP服务器
+func DialWebSocket(ctx context.Context, serverURL string) (Transport, error) {
+	dialer := websocket.Dialer{}
+	conn, _, err := dialer.DialContext(ctx, serverURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("连接MCP服务器失败: %v", err)
+	}
+	return &websocketTransport{conn: conn}, nil
+}
+
+// Send 发送一个文本帧
+func (t *websocketTransport) Send(data []byte) error {
+	t.writeMu.Lock()
+	defer t.writeMu.Unlock()
+	return t.conn.WriteMessage(websocket.TextMessage, data)
+}
+
+// Receive 读取下一帧
+func (t *websocketTransport) Receive() ([]byte, error) {
+	_, data, err := t.conn.ReadMessage()
+	return data, err
+}
+
+// Close 关闭WebSocket连接
+func (t *websocketTransport) Close() error {
+	return t.conn.Close()
+}
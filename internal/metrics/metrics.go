@@ -0,0 +1,138 @@
+package metrics
+
+import (
+	"database/sql"
+	"mcp-ai-client/internal/mcp"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// namespace 指标名前缀
+const namespace = "mcp_ai_client"
+
+// mcpCallBuckets MCP调用耗时分桶（秒），AI工具调用通常在秒级到分钟级
+var mcpCallBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 20, 30, 60, 120}
+
+// Metrics 服务指标，以 Prometheus 文本格式输出
+type Metrics struct {
+	registry *prometheus.Registry
+
+	httpDuration    *prometheus.HistogramVec
+	mcpCallDuration *prometheus.HistogramVec
+	mcpCallErrors   *prometheus.CounterVec
+	mcpInflight     *prometheus.GaugeVec
+	mcpReconnects   prometheus.Counter
+}
+
+// New 创建并注册指标
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "HTTP请求耗时，按路由、方法和状态码区分",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		mcpCallDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "mcp_call_duration_seconds",
+			Help:      "MCP工具调用耗时，按工具名和结果(success/error)区分",
+			Buckets:   mcpCallBuckets,
+		}, []string{"tool", "status"}),
+		mcpCallErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "mcp_call_errors_total",
+			Help:      "MCP工具调用失败次数，按工具名和错误类别区分",
+		}, []string{"tool", "error_class"}),
+		mcpInflight: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "mcp_inflight_requests",
+			Help:      "正在等待响应的MCP工具调用数",
+		}, []string{"tool"}),
+		mcpReconnects: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "mcp_reconnects_total",
+			Help:      "MCP连接断开后重连成功的次数",
+		}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.httpDuration,
+		m.mcpCallDuration,
+		m.mcpCallErrors,
+		m.mcpInflight,
+		m.mcpReconnects,
+	)
+	return m
+}
+
+// Handler 返回 /metrics 处理器
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// GinMiddleware 记录HTTP请求耗时，路由取注册时的路径模板（如 /api/v1/tools/:name）以控制标签基数
+func (m *Metrics) GinMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		m.httpDuration.WithLabelValues(c.Request.Method, route, strconv.Itoa(c.Writer.Status())).
+			Observe(time.Since(start).Seconds())
+	}
+}
+
+// InstrumentMCP 统计MCP客户端的工具调用和重连，每个客户端（包括热加载后新建的）调用一次
+func (m *Metrics) InstrumentMCP(client *mcp.MCPClient) {
+	client.ObserveCalls(m)
+
+	var mu sync.Mutex
+	connected := client.State() == mcp.StateConnected
+	client.OnStateChange(func(state mcp.ConnectionState) {
+		if state != mcp.StateConnected {
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if connected {
+			m.mcpReconnects.Inc()
+		}
+		connected = true
+	})
+}
+
+// ToolCallStarted 实现 mcp.CallObserver
+func (m *Metrics) ToolCallStarted(tool string) {
+	m.mcpInflight.WithLabelValues(tool).Inc()
+}
+
+// ToolCallFinished 实现 mcp.CallObserver
+func (m *Metrics) ToolCallFinished(tool string, duration time.Duration, err error) {
+	m.mcpInflight.WithLabelValues(tool).Dec()
+
+	status := "success"
+	if err != nil {
+		status = "error"
+		m.mcpCallErrors.WithLabelValues(tool, mcp.ClassifyError(err)).Inc()
+	}
+	m.mcpCallDuration.WithLabelValues(tool, status).Observe(duration.Seconds())
+}
+
+// CollectDBStats 注册数据库连接池指标，每次抓取时调用 stats 读取当前连接池（配置热加载后可能已替换）
+func (m *Metrics) CollectDBStats(stats func() (sql.DBStats, bool)) {
+	m.registry.MustRegister(newDBStatsCollector(stats))
+}
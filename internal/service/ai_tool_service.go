@@ -0,0 +1,444 @@
+package service
+
+import (
+	"mcp-ai-client/internal/mcp"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// AIToolConfig AI工具默认参数
+type AIToolConfig struct {
+	ResponseLanguage           string `yaml:"response_language"`            // zh-CN、en-US 或 auto，默认 zh-CN
+	DefaultProvider            string `yaml:"default_provider"`             // 默认 ollama
+	DefaultModel               string `yaml:"default_model"`                // 默认 llama2:7b
+	IncludeLanguageInstruction bool   `yaml:"include_language_instruction"` // 是否在指令末尾附加语言要求
+}
+
+// WithDefaults 填充未配置的AI参数
+func (c AIToolConfig) WithDefaults() AIToolConfig {
+	if c.ResponseLanguage == "" {
+		c.ResponseLanguage = "zh-CN"
+	}
+	if c.DefaultProvider == "" {
+		c.DefaultProvider = "ollama"
+	}
+	if c.DefaultModel == "" {
+		c.DefaultModel = "llama2:7b"
+	}
+	return c
+}
+
+// ChatRequest 5.1 AI对话参数
+type ChatRequest struct {
+	Prompt      string  `json:"prompt" binding:"required"`
+	Provider    string  `json:"provider"`
+	Model       string  `json:"model"`
+	MaxTokens   int     `json:"max_tokens"`
+	Temperature float64 `json:"temperature"`
+}
+
+// ChatResult ai_chat 工具返回的结构化结果
+type ChatResult struct {
+	Tool     string `json:"tool"`
+	Status   string `json:"status"`
+	Prompt   string `json:"prompt"`
+	Provider string `json:"provider"`
+	Model    string `json:"model"`
+	Response string `json:"response"`
+}
+
+// FileManagerRequest 5.2 AI文件管理参数
+type FileManagerRequest struct {
+	Instruction   string `json:"instruction" binding:"required"`
+	TargetPath    string `json:"target_path"`
+	OperationMode string `json:"operation_mode"`
+	Provider      string `json:"provider"`
+	Model         string `json:"model"`
+}
+
+// DataProcessorRequest 5.3 AI数据处理参数
+type DataProcessorRequest struct {
+	Instruction   string `json:"instruction" binding:"required"`
+	InputData     string `json:"input_data" binding:"required"`
+	DataType      string `json:"data_type"`
+	OutputFormat  string `json:"output_format"`
+	OperationMode string `json:"operation_mode"`
+	Provider      string `json:"provider"`
+	Model         string `json:"model"`
+}
+
+// APIClientRequest 5.4 AI网络请求参数
+type APIClientRequest struct {
+	Instruction      string `json:"instruction" binding:"required"`
+	BaseURL          string `json:"base_url"`
+	AuthInfo         string `json:"auth_info"`
+	RequestMode      string `json:"request_mode"`
+	ResponseAnalysis bool   `json:"response_analysis"`
+	Provider         string `json:"provider"`
+	Model            string `json:"model"`
+}
+
+// QueryWithAnalysisRequest 5.5 AI数据库查询参数
+type QueryWithAnalysisRequest struct {
+	Description  string `json:"description" binding:"required"`
+	AnalysisType string `json:"analysis_type"`
+	TableName    string `json:"table_name"`
+	Context      string `json:"context"`
+	InsightLevel string `json:"insight_level"`
+	Provider     string `json:"provider"`
+	Model        string `json:"model"`
+}
+
+// queryWithAnalysisResult ai_query_with_analysis 工具返回的结构化结果
+type queryWithAnalysisResult struct {
+	Tool         string      `json:"tool"`
+	Status       string      `json:"status"`
+	Description  string      `json:"description"`
+	AnalysisType string      `json:"analysis_type"`
+	TableName    string      `json:"table_name"`
+	Provider     string      `json:"provider"`
+	Model        string      `json:"model"`
+	Result       interface{} `json:"result"`
+	Analysis     string      `json:"analysis"`
+	Insights     string      `json:"insights"`
+}
+
+// AIToolService AI工具参数构建和结果整理，HTTP接口和命令行共用
+type AIToolService struct {
+	config AIToolConfig
+}
+
+// NewAIToolService 创建AI工具服务
+func NewAIToolService(config AIToolConfig) *AIToolService {
+	return &AIToolService{config: config.WithDefaults()}
+}
+
+// Config 返回生效的AI配置
+func (s *AIToolService) Config() AIToolConfig {
+	return s.config
+}
+
+// LanguageInstruction 根据配置生成语言指令
+func (s *AIToolService) LanguageInstruction() string {
+	if !s.config.IncludeLanguageInstruction {
+		return ""
+	}
+
+	switch s.config.ResponseLanguage {
+	case "zh-CN":
+		return "请用中文回答。"
+	case "en-US":
+		return "Please respond in English."
+	case "auto":
+		return "请根据用户的语言进行回答。Please respond in the user's language."
+	default:
+		return "请用中文回答。"
+	}
+}
+
+// applyDefaults 应用默认AI参数
+func (s *AIToolService) applyDefaults(args map[string]interface{}) {
+	if _, exists := args["provider"]; !exists {
+		args["provider"] = s.config.DefaultProvider
+	}
+	if _, exists := args["model"]; !exists {
+		args["model"] = s.config.DefaultModel
+	}
+}
+
+// ===== 调用参数 =====
+
+// ChatArgs 构建 ai_chat 调用参数
+func (s *AIToolService) ChatArgs(request ChatRequest) map[string]interface{} {
+	args := map[string]interface{}{
+		"prompt": request.Prompt + " " + s.LanguageInstruction(),
+	}
+
+	if request.Provider != "" {
+		args["provider"] = request.Provider
+	}
+	if request.Model != "" {
+		args["model"] = request.Model
+	}
+	if request.MaxTokens > 0 {
+		args["max_tokens"] = request.MaxTokens
+	}
+	if request.Temperature > 0 {
+		args["temperature"] = request.Temperature
+	}
+
+	s.applyDefaults(args)
+	return args
+}
+
+// FileManagerArgs 构建 ai_file_manager 调用参数
+func (s *AIToolService) FileManagerArgs(request FileManagerRequest) map[string]interface{} {
+	args := map[string]interface{}{
+		"instruction": request.Instruction + " " + s.LanguageInstruction(),
+	}
+
+	// 将相对/特殊路径重写为调用方的工作目录下的安全绝对路径，避免影响服务提供方
+	if request.TargetPath != "" {
+		var cleaned string = request.TargetPath
+		// 去除可能的危险前缀
+		cleaned = strings.TrimSpace(cleaned)
+		cleaned = strings.TrimPrefix(cleaned, "~")
+		cleaned = strings.ReplaceAll(cleaned, "..", "")
+		// 统一将相对路径锚定到当前进程工作目录
+		cwd, _ := os.Getwd()
+		abs := cleaned
+		if !filepath.IsAbs(cleaned) {
+			abs = filepath.Join(cwd, cleaned)
+		}
+		// 规范化
+		abs = filepath.Clean(abs)
+		args["target_path"] = abs
+	}
+	if request.OperationMode != "" {
+		args["operation_mode"] = request.OperationMode
+	}
+	if request.Provider != "" {
+		args["provider"] = request.Provider
+	}
+	if request.Model != "" {
+		args["model"] = request.Model
+	}
+
+	s.applyDefaults(args)
+	return args
+}
+
+// DataProcessorArgs 构建 ai_data_processor 调用参数
+func (s *AIToolService) DataProcessorArgs(request DataProcessorRequest) map[string]interface{} {
+	args := map[string]interface{}{
+		"instruction": request.Instruction + " " + s.LanguageInstruction(),
+		"input_data":  request.InputData,
+	}
+
+	if request.DataType != "" {
+		args["data_type"] = request.DataType
+	}
+	if request.OutputFormat != "" {
+		args["output_format"] = request.OutputFormat
+	}
+	if request.OperationMode != "" {
+		args["operation_mode"] = request.OperationMode
+	}
+	if request.Provider != "" {
+		args["provider"] = request.Provider
+	}
+	if request.Model != "" {
+		args["model"] = request.Model
+	}
+
+	s.applyDefaults(args)
+	return args
+}
+
+// APIClientArgs 构建 ai_api_client 调用参数
+func (s *AIToolService) APIClientArgs(request APIClientRequest) map[string]interface{} {
+	args := map[string]interface{}{
+		"instruction": request.Instruction + " " + s.LanguageInstruction(),
+	}
+
+	if request.BaseURL != "" {
+		args["base_url"] = request.BaseURL
+	}
+	if request.AuthInfo != "" {
+		args["auth_info"] = request.AuthInfo
+	}
+	if request.RequestMode != "" {
+		args["request_mode"] = request.RequestMode
+	}
+	args["response_analysis"] = request.ResponseAnalysis
+	if request.Provider != "" {
+		args["provider"] = request.Provider
+	}
+	if request.Model != "" {
+		args["model"] = request.Model
+	}
+
+	s.applyDefaults(args)
+	return args
+}
+
+// QueryWithAnalysisArgs 构建 ai_query_with_analysis 调用参数
+func (s *AIToolService) QueryWithAnalysisArgs(request QueryWithAnalysisRequest) map[string]interface{} {
+	args := map[string]interface{}{
+		"description": request.Description + " " + s.LanguageInstruction(),
+	}
+
+	if request.AnalysisType != "" {
+		args["analysis_type"] = request.AnalysisType
+	}
+	if request.TableName != "" {
+		args["table_name"] = request.TableName
+	}
+	if request.Context != "" {
+		args["context"] = request.Context
+	}
+	if request.InsightLevel != "" {
+		args["insight_level"] = request.InsightLevel
+	}
+	if request.Provider != "" {
+		args["provider"] = request.Provider
+	}
+	if request.Model != "" {
+		args["model"] = request.Model
+	}
+
+	s.applyDefaults(args)
+	return args
+}
+
+// ===== 调用结果 =====
+
+// ChatResponse 整理 ai_chat 调用结果
+func (s *AIToolService) ChatResponse(request ChatRequest, result *mcp.ToolCallResult, start time.Time) map[string]interface{} {
+	var responseData map[string]interface{}
+	var mcpResponse ChatResult
+	if err := result.DecodeStructured(&mcpResponse); err == nil {
+		responseData = map[string]interface{}{
+			"tool":     "ai_chat",
+			"status":   mcpResponse.Status,
+			"prompt":   request.Prompt,
+			"response": mcpResponse.Response,
+			"duration": time.Since(start).String(),
+		}
+		if mcpResponse.Provider != "" {
+			responseData["provider"] = mcpResponse.Provider
+		}
+		if mcpResponse.Model != "" {
+			responseData["model"] = mcpResponse.Model
+		}
+	} else {
+		responseData = map[string]interface{}{
+			"tool":     "ai_chat",
+			"status":   "success",
+			"prompt":   request.Prompt,
+			"response": result.Text(),
+			"duration": time.Since(start).String(),
+		}
+	}
+
+	AppendResultContent(responseData, result)
+	return responseData
+}
+
+// InstructionResponse 整理以 instruction 为输入的工具（文件管理、数据处理、网络请求）的调用结果
+func (s *AIToolService) InstructionResponse(toolName, instruction string, result *mcp.ToolCallResult, start time.Time) map[string]interface{} {
+	responseData := map[string]interface{}{
+		"tool":        toolName,
+		"status":      "success",
+		"instruction": instruction,
+		"result":      result.Text(),
+		"duration":    time.Since(start).String(),
+	}
+
+	AppendResultContent(responseData, result)
+	return responseData
+}
+
+// QueryWithAnalysisResponse 整理 ai_query_with_analysis 调用结果
+func (s *AIToolService) QueryWithAnalysisResponse(request QueryWithAnalysisRequest, result *mcp.ToolCallResult, start time.Time) map[string]interface{} {
+	var responseData map[string]interface{}
+	var mcpResponse queryWithAnalysisResult
+	if err := result.DecodeStructured(&mcpResponse); err == nil {
+		responseData = map[string]interface{}{
+			"tool":          "ai_query_with_analysis",
+			"status":        mcpResponse.Status,
+			"description":   request.Description,
+			"analysis_type": mcpResponse.AnalysisType,
+			"duration":      time.Since(start).String(),
+		}
+
+		if mcpResponse.TableName != "" {
+			responseData["table_name"] = mcpResponse.TableName
+		}
+		if mcpResponse.Provider != "" {
+			responseData["provider"] = mcpResponse.Provider
+		}
+		if mcpResponse.Model != "" {
+			responseData["model"] = mcpResponse.Model
+		}
+		if mcpResponse.Result != nil {
+			responseData["result"] = mcpResponse.Result
+		}
+		if mcpResponse.Analysis != "" {
+			responseData["analysis"] = mcpResponse.Analysis
+		}
+		if mcpResponse.Insights != "" {
+			responseData["insights"] = mcpResponse.Insights
+		}
+	} else {
+		responseData = map[string]interface{}{
+			"tool":        "ai_query_with_analysis",
+			"status":      "success",
+			"description": request.Description,
+			"result":      result.Text(),
+			"duration":    time.Since(start).String(),
+		}
+	}
+
+	AppendResultContent(responseData, result)
+	return responseData
+}
+
+// AppendResultContent 将文本以外的内容块（图片、音频、资源）和结构化结果附加到响应中
+// 文本块已合并到 response/result 字段
+func AppendResultContent(responseData map[string]interface{}, result *mcp.ToolCallResult) {
+	if blocks := result.NonText(); len(blocks) > 0 {
+		attachments := make([]map[string]interface{}, 0, len(blocks))
+		for _, block := range blocks {
+			attachments = append(attachments, RenderContentBlock(block))
+		}
+		responseData["attachments"] = attachments
+	}
+	if result.StructuredContent != nil {
+		responseData["structured_content"] = result.StructuredContent
+	}
+}
+
+// RenderContentBlock 将单个内容块转换为响应格式
+func RenderContentBlock(block mcp.Content) map[string]interface{} {
+	rendered := map[string]interface{}{"type": block.Type}
+	switch block.Type {
+	case mcp.ContentImage, mcp.ContentAudio:
+		rendered["mime_type"] = block.MimeType
+		rendered["data"] = block.Data
+		rendered["data_url"] = block.DataURL()
+	case mcp.ContentResourceLink:
+		rendered["uri"] = block.URI
+		if block.Name != "" {
+			rendered["name"] = block.Name
+		}
+		if block.Description != "" {
+			rendered["description"] = block.Description
+		}
+		if block.MimeType != "" {
+			rendered["mime_type"] = block.MimeType
+		}
+	case mcp.ContentResource:
+		if block.Resource != nil {
+			rendered["uri"] = block.Resource.URI
+			if block.Resource.MimeType != "" {
+				rendered["mime_type"] = block.Resource.MimeType
+			}
+			if block.Resource.Text != "" {
+				rendered["text"] = block.Resource.Text
+			}
+			if block.Resource.Blob != "" {
+				rendered["blob"] = block.Resource.Blob
+			}
+		}
+	default:
+		// 未知类型原样透传
+		rendered["block"] = block
+	}
+	if len(block.Annotations) > 0 {
+		rendered["annotations"] = block.Annotations
+	}
+	return rendered
+}
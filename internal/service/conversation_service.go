@@ -0,0 +1,363 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"mcp-ai-client/internal/database"
+	"mcp-ai-client/internal/logging"
+	"sort"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// 消息角色
+const (
+	RoleSystem    = "system"
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+)
+
+// ErrConversationNotFound 会话不存在或已删除
+var ErrConversationNotFound = errors.New("会话不存在")
+
+// restorePageSize 从数据库恢复会话时每次读取的消息数
+const restorePageSize = 500
+
+// ConversationConfig 多轮对话配置
+type ConversationConfig struct {
+	MaxTurns         int `yaml:"max_turns"`         // 每次发送的最多历史轮数（一问一答为一轮），默认10
+	MaxTokens        int `yaml:"max_tokens"`        // 每次发送的历史估算token上限，默认4000
+	MaxConversations int `yaml:"max_conversations"` // 内存中保留的会话数上限，超过时淘汰最久未活跃的，默认1000
+}
+
+// withDefaults 填充未配置的对话参数
+func (cc ConversationConfig) withDefaults() ConversationConfig {
+	if cc.MaxTurns <= 0 {
+		cc.MaxTurns = 10
+	}
+	if cc.MaxTokens <= 0 {
+		cc.MaxTokens = 4000
+	}
+	if cc.MaxConversations <= 0 {
+		cc.MaxConversations = 1000
+	}
+	return cc
+}
+
+// Message 会话中的一条消息
+type Message struct {
+	Role      string    `json:"role"`
+	Content   string    `json:"content"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Conversation 会话及其完整消息历史
+type Conversation struct {
+	ID           string    `json:"id"`
+	Title        string    `json:"title,omitempty"`
+	SystemPrompt string    `json:"system_prompt,omitempty"`
+	Provider     string    `json:"provider,omitempty"`
+	Model        string    `json:"model,omitempty"`
+	Messages     []Message `json:"messages"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// ConversationService 多轮对话服务：保存会话历史并按预算裁剪发送给模型的上下文
+// 配置了 store 时会话和消息同时写入MySQL：内存中被淘汰或服务重启后的会话可从数据库恢复，
+// 删除为软删除以保留审计记录；数据库写入失败只记录日志，不影响对话
+type ConversationService struct {
+	mu            sync.Mutex
+	config        ConversationConfig
+	store         *database.MySQLClient
+	conversations map[string]*Conversation
+	logger        *slog.Logger
+}
+
+// NewConversationService 创建对话服务，store 为 nil 时仅保存在内存中，logger 为 nil 时使用 slog.Default()
+func NewConversationService(config ConversationConfig, store *database.MySQLClient, logger *slog.Logger) *ConversationService {
+	return &ConversationService{
+		config:        config.withDefaults(),
+		store:         store,
+		conversations: make(map[string]*Conversation),
+		logger:        logging.OrDefault(logger),
+	}
+}
+
+// Reconfigure 更新对话配置和存储（配置热加载时调用），内存中的会话保留
+// 会话数超过新的上限时在下次创建或恢复会话时淘汰
+func (s *ConversationService) Reconfigure(config ConversationConfig, store *database.MySQLClient) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.config = config.withDefaults()
+	s.store = store
+}
+
+// currentConfig 返回当前对话配置
+func (s *ConversationService) currentConfig() ConversationConfig {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.config
+}
+
+// Create 创建新会话
+func (s *ConversationService) Create(ctx context.Context, title, systemPrompt, provider, model string) (*Conversation, error) {
+	id, err := newConversationID()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	conv := &Conversation{
+		ID:           id,
+		Title:        title,
+		SystemPrompt: systemPrompt,
+		Provider:     provider,
+		Model:        model,
+		Messages:     []Message{},
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+
+	s.mu.Lock()
+	s.evictLocked()
+	s.conversations[id] = conv
+	store := s.store
+	s.mu.Unlock()
+
+	if store != nil {
+		record := &database.ConversationRecord{
+			ID:           id,
+			Title:        title,
+			SystemPrompt: systemPrompt,
+			Provider:     provider,
+			Model:        model,
+			CreatedAt:    now,
+			UpdatedAt:    now,
+		}
+		if err := store.InsertConversation(ctx, record); err != nil {
+			s.logger.ErrorContext(ctx, "会话持久化失败", "conversation_id", id, "error", err)
+		}
+	}
+
+	s.logger.InfoContext(ctx, "创建会话", "conversation_id", id)
+	return conv.clone(), nil
+}
+
+// Get 返回会话副本，内存中没有时尝试从数据库恢复
+func (s *ConversationService) Get(ctx context.Context, id string) (*Conversation, error) {
+	s.mu.Lock()
+	conv, ok := s.conversations[id]
+	if ok {
+		conv = conv.clone()
+	}
+	store := s.store
+	s.mu.Unlock()
+
+	if ok {
+		return conv, nil
+	}
+	if store == nil {
+		return nil, ErrConversationNotFound
+	}
+	conv, err := s.load(ctx, store, id)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// 并发恢复时以先写入的为准
+	if existing, ok := s.conversations[id]; ok {
+		return existing.clone(), nil
+	}
+	s.evictLocked()
+	s.conversations[id] = conv
+	return conv.clone(), nil
+}
+
+// Append 追加一组消息（通常是一问一答）
+func (s *ConversationService) Append(ctx context.Context, id string, messages ...Message) error {
+	// 确保会话在内存中（可能刚被淘汰）
+	if _, err := s.Get(ctx, id); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	conv, ok := s.conversations[id]
+	if !ok {
+		s.mu.Unlock()
+		return ErrConversationNotFound
+	}
+	conv.Messages = append(conv.Messages, messages...)
+	conv.UpdatedAt = time.Now()
+	store := s.store
+	s.mu.Unlock()
+
+	if store != nil {
+		records := make([]database.MessageRecord, 0, len(messages))
+		for _, msg := range messages {
+			records = append(records, database.MessageRecord{Role: msg.Role, Content: msg.Content, CreatedAt: msg.CreatedAt})
+		}
+		if err := store.InsertMessages(ctx, id, records); err != nil {
+			s.logger.ErrorContext(ctx, "消息持久化失败", "conversation_id", id, "error", err)
+		}
+	}
+	return nil
+}
+
+// Delete 删除会话
+func (s *ConversationService) Delete(ctx context.Context, id string) error {
+	s.mu.Lock()
+	_, ok := s.conversations[id]
+	delete(s.conversations, id)
+	store := s.store
+	s.mu.Unlock()
+
+	if store != nil {
+		if !ok {
+			// 只在数据库中存在的会话
+			if _, err := store.GetConversation(ctx, id); err == nil {
+				ok = true
+			} else if err != sql.ErrNoRows {
+				return err
+			}
+		}
+		if ok {
+			if err := store.DeleteConversation(ctx, id); err != nil {
+				return err
+			}
+		}
+	}
+
+	if !ok {
+		return ErrConversationNotFound
+	}
+	s.logger.InfoContext(ctx, "删除会话", "conversation_id", id)
+	return nil
+}
+
+// load 从数据库恢复会话及全部消息
+func (s *ConversationService) load(ctx context.Context, store *database.MySQLClient, id string) (*Conversation, error) {
+	record, err := store.GetConversation(ctx, id)
+	if err == sql.ErrNoRows {
+		return nil, ErrConversationNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	conv := &Conversation{
+		ID:           record.ID,
+		Title:        record.Title,
+		SystemPrompt: record.SystemPrompt,
+		Provider:     record.Provider,
+		Model:        record.Model,
+		Messages:     []Message{},
+		CreatedAt:    record.CreatedAt,
+		UpdatedAt:    record.UpdatedAt,
+	}
+
+	filter := database.MessageFilter{ConversationID: id, Limit: restorePageSize}
+	for {
+		page, total, err := store.ListMessages(ctx, filter)
+		if err != nil {
+			return nil, err
+		}
+		for _, msg := range page {
+			conv.Messages = append(conv.Messages, Message{Role: msg.Role, Content: msg.Content, CreatedAt: msg.CreatedAt})
+		}
+		filter.Offset += len(page)
+		if len(page) == 0 || filter.Offset >= total {
+			break
+		}
+	}
+
+	s.logger.InfoContext(ctx, "从数据库恢复会话", "conversation_id", id, "messages", len(conv.Messages))
+	return conv, nil
+}
+
+// BuildContext 组装本轮发送给模型的消息：系统提示 + 裁剪后的历史 + 本轮用户消息
+// 历史从最新往前保留，同时受轮数和估算token预算限制；本轮用户消息始终保留。
+// 返回的 truncated 表示是否有更早的历史被裁掉
+func (s *ConversationService) BuildContext(conv *Conversation, userMessage Message) (messages []Message, truncated bool) {
+	config := s.currentConfig()
+	budget := config.MaxTokens - EstimateTokens(conv.SystemPrompt) - EstimateTokens(userMessage.Content)
+	maxMessages := config.MaxTurns * 2
+
+	history := conv.Messages
+	start := len(history)
+	for start > 0 && len(history)-start < maxMessages {
+		cost := EstimateTokens(history[start-1].Content)
+		if cost > budget {
+			break
+		}
+		budget -= cost
+		start--
+	}
+	// 不以孤立的模型回答开头，避免上下文缺少对应的问题
+	for start < len(history) && history[start].Role == RoleAssistant {
+		start++
+	}
+
+	if conv.SystemPrompt != "" {
+		messages = append(messages, Message{Role: RoleSystem, Content: conv.SystemPrompt})
+	}
+	messages = append(messages, history[start:]...)
+	messages = append(messages, userMessage)
+	return messages, start > 0
+}
+
+// EstimateTokens 粗略估算文本的token数：ASCII 约4个字符1个token，其余字符（如中文）按1个字符1个token
+func EstimateTokens(text string) int {
+	ascii, other := 0, 0
+	for _, r := range text {
+		if r < utf8.RuneSelf {
+			ascii++
+		} else {
+			other++
+		}
+	}
+	return (ascii+3)/4 + other
+}
+
+// evictLocked 会话数达到上限时淘汰最久未活跃的会话
+func (s *ConversationService) evictLocked() {
+	excess := len(s.conversations) - s.config.MaxConversations + 1
+	if excess <= 0 {
+		return
+	}
+
+	convs := make([]*Conversation, 0, len(s.conversations))
+	for _, conv := range s.conversations {
+		convs = append(convs, conv)
+	}
+	sort.Slice(convs, func(i, j int) bool {
+		return convs[i].UpdatedAt.Before(convs[j].UpdatedAt)
+	})
+	for _, conv := range convs[:excess] {
+		delete(s.conversations, conv.ID)
+		s.logger.Info("会话数达到上限，淘汰会话", "conversation_id", conv.ID)
+	}
+}
+
+// clone 复制会话，避免调用方修改共享的消息切片
+func (c *Conversation) clone() *Conversation {
+	cp := *c
+	cp.Messages = append([]Message{}, c.Messages...)
+	return &cp
+}
+
+// newConversationID 生成随机会话ID
+func newConversationID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
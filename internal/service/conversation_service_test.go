@@ -0,0 +1,157 @@
+package service
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// history 按顺序生成一问一答的历史消息，contents 依次为问题和回答
+func history(contents ...string) []Message {
+	messages := make([]Message, len(contents))
+	for i, content := range contents {
+		role := RoleUser
+		if i%2 == 1 {
+			role = RoleAssistant
+		}
+		messages[i] = Message{Role: role, Content: content}
+	}
+	return messages
+}
+
+// tokens 生成估算为 n 个token的文本（每个中文字符1个token）
+func tokens(n int) string {
+	return strings.Repeat("字", n)
+}
+
+func TestBuildContext(t *testing.T) {
+	q := func(i int) string { return "问" + strings.Repeat("字", 9) + string(rune('0'+i)) } // 11 token
+	a := func(i int) string { return "答" + strings.Repeat("字", 9) + string(rune('0'+i)) }
+
+	tests := []struct {
+		name          string
+		config        ConversationConfig
+		systemPrompt  string
+		history       []Message
+		user          string
+		want          []string // 按顺序的消息内容
+		wantTruncated bool
+	}{
+		{
+			name:    "没有历史",
+			config:  ConversationConfig{MaxTurns: 2, MaxTokens: 1000},
+			history: nil,
+			user:    "你好",
+			want:    []string{"你好"},
+		},
+		{
+			name:    "历史未超限",
+			config:  ConversationConfig{MaxTurns: 2, MaxTokens: 1000},
+			history: history(q(1), a(1), q(2), a(2)),
+			user:    "你好",
+			want:    []string{q(1), a(1), q(2), a(2), "你好"},
+		},
+		{
+			name:          "超过轮数只保留最近的轮次",
+			config:        ConversationConfig{MaxTurns: 2, MaxTokens: 1000},
+			history:       history(q(1), a(1), q(2), a(2), q(3), a(3)),
+			user:          "你好",
+			want:          []string{q(2), a(2), q(3), a(3), "你好"},
+			wantTruncated: true,
+		},
+		{
+			// 预算 50-2=48：a3、q3、a2、q2 共44，再加 a1 超出
+			name:          "超过token预算",
+			config:        ConversationConfig{MaxTurns: 10, MaxTokens: 50},
+			history:       history(q(1), a(1), q(2), a(2), q(3), a(3)),
+			user:          "你好",
+			want:          []string{q(2), a(2), q(3), a(3), "你好"},
+			wantTruncated: true,
+		},
+		{
+			// 预算 40-2=38：a3、q3、a2 共33，q2 超出；不以孤立的回答 a2 开头
+			name:          "裁剪后不以模型回答开头",
+			config:        ConversationConfig{MaxTurns: 10, MaxTokens: 40},
+			history:       history(q(1), a(1), q(2), a(2), q(3), a(3)),
+			user:          "你好",
+			want:          []string{q(3), a(3), "你好"},
+			wantTruncated: true,
+		},
+		{
+			name:          "最近一条历史超过预算时不保留更早的历史",
+			config:        ConversationConfig{MaxTurns: 10, MaxTokens: 100},
+			history:       history(q(1), a(1), q(2), tokens(200)),
+			user:          "你好",
+			want:          []string{"你好"},
+			wantTruncated: true,
+		},
+		{
+			name:          "本轮消息超过预算时仍然发送",
+			config:        ConversationConfig{MaxTurns: 10, MaxTokens: 100},
+			history:       history(q(1), a(1)),
+			user:          tokens(150),
+			want:          []string{tokens(150)},
+			wantTruncated: true,
+		},
+		{
+			name:         "系统提示在最前",
+			config:       ConversationConfig{MaxTurns: 10, MaxTokens: 1000},
+			systemPrompt: "你是助手",
+			history:      history(q(1), a(1)),
+			user:         "你好",
+			want:         []string{"你是助手", q(1), a(1), "你好"},
+		},
+		{
+			// 系统提示占用预算 40，剩余 60-40-2=18 只够 a1，孤立的回答随后被去掉
+			name:          "系统提示计入预算且始终保留",
+			config:        ConversationConfig{MaxTurns: 10, MaxTokens: 60},
+			systemPrompt:  tokens(40),
+			history:       history(q(1), a(1)),
+			user:          "你好",
+			want:          []string{tokens(40), "你好"},
+			wantTruncated: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewConversationService(tt.config, nil, nil)
+			conv := &Conversation{SystemPrompt: tt.systemPrompt, Messages: tt.history}
+			messages, truncated := s.BuildContext(conv, Message{Role: RoleUser, Content: tt.user})
+
+			var got []string
+			for _, m := range messages {
+				got = append(got, m.Content)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("BuildContext() = %q, want %q", got, tt.want)
+			}
+			if truncated != tt.wantTruncated {
+				t.Errorf("truncated = %v, want %v", truncated, tt.wantTruncated)
+			}
+			if tt.systemPrompt != "" && messages[0].Role != RoleSystem {
+				t.Errorf("第一条消息角色 = %s, want system", messages[0].Role)
+			}
+			if last := messages[len(messages)-1]; last.Role != RoleUser || last.Content != tt.user {
+				t.Errorf("最后一条消息 = %+v, want 本轮用户消息", last)
+			}
+		})
+	}
+}
+
+func TestEstimateTokens(t *testing.T) {
+	tests := []struct {
+		text string
+		want int
+	}{
+		{"", 0},
+		{"abcd", 1},
+		{"abcde", 2},
+		{"你好", 2},
+		{"hi你好", 3},
+	}
+	for _, tt := range tests {
+		if got := EstimateTokens(tt.text); got != tt.want {
+			t.Errorf("EstimateTokens(%q) = %d, want %d", tt.text, got, tt.want)
+		}
+	}
+}
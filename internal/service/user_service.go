@@ -1,9 +1,11 @@
 package service
 
 import (
+	"context"
 	"fmt"
-	"log"
+	"log/slog"
 	"mcp-ai-client/internal/database"
+	"mcp-ai-client/internal/logging"
 	"strconv"
 	"strings"
 	"time"
@@ -13,13 +15,15 @@ import (
 type UserService struct {
 	mysqlClient *database.MySQLClient
 	userTable   string // 用户表名
+	logger      *slog.Logger
 }
 
-// NewUserService 创建用户服务
-func NewUserService(mysqlClient *database.MySQLClient, userTable string) *UserService {
+// NewUserService 创建用户服务，logger 为 nil 时使用 slog.Default()
+func NewUserService(mysqlClient *database.MySQLClient, userTable string, logger *slog.Logger) *UserService {
 	return &UserService{
 		mysqlClient: mysqlClient,
 		userTable:   userTable,
+		logger:      logging.OrDefault(logger),
 	}
 }
 
@@ -34,14 +38,14 @@ type User struct {
 }
 
 // GetAllUsers 获取所有用户 - 传统方法
-func (s *UserService) GetAllUsers() ([]User, error) {
+func (s *UserService) GetAllUsers(ctx context.Context) ([]User, error) {
 	start := time.Now()
-	log.Printf("🔍 [传统查询] 开始查询所有用户...")
+	s.logger.DebugContext(ctx, "开始查询所有用户", "table", s.userTable)
 
 	// 直接调用数据库
-	data, err := s.mysqlClient.QueryUser(s.userTable)
+	data, err := s.mysqlClient.QueryUser(ctx, s.userTable)
 	if err != nil {
-		log.Printf("❌ [传统查询] 查询失败: %v", err)
+		s.logger.ErrorContext(ctx, "查询用户失败", "table", s.userTable, "error", err)
 		return nil, fmt.Errorf("查询用户失败: %v", err)
 	}
 
@@ -103,20 +107,20 @@ func (s *UserService) GetAllUsers() ([]User, error) {
 	}
 
 	duration := time.Since(start)
-	log.Printf("✅ [传统查询] 查询完成，共找到 %d 个用户，耗时: %v", len(users), duration)
+	s.logger.InfoContext(ctx, "查询所有用户完成", "count", len(users), "duration", duration)
 
 	return users, nil
 }
 
 // GetUserByID 根据ID获取用户 - 传统方法
-func (s *UserService) GetUserByID(id int) (*User, error) {
+func (s *UserService) GetUserByID(ctx context.Context, id int) (*User, error) {
 	start := time.Now()
-	log.Printf("🔍 [传统查询] 开始查询用户 ID: %d", id)
+	s.logger.DebugContext(ctx, "开始查询用户", "id", id)
 
 	// 直接调用数据库
-	data, err := s.mysqlClient.QueryUserByID(id, s.userTable)
+	data, err := s.mysqlClient.QueryUserByID(ctx, id, s.userTable)
 	if err != nil {
-		log.Printf("❌ [传统查询] 查询失败: %v", err)
+		s.logger.ErrorContext(ctx, "查询用户失败", "id", id, "error", err)
 		return nil, fmt.Errorf("查询用户失败: %v", err)
 	}
 
@@ -147,18 +151,18 @@ func (s *UserService) GetUserByID(id int) (*User, error) {
 	}
 
 	duration := time.Since(start)
-	log.Printf("✅ [传统查询] 查询完成，用户: %s，耗时: %v", user.Name, duration)
+	s.logger.InfoContext(ctx, "查询用户完成", "id", id, "duration", duration)
 
 	return user, nil
 }
 
 // SearchUsers 搜索用户 - 传统方法
-func (s *UserService) SearchUsers(keyword string) ([]User, error) {
+func (s *UserService) SearchUsers(ctx context.Context, keyword string) ([]User, error) {
 	start := time.Now()
-	log.Printf("🔍 [传统查询] 开始搜索用户，关键词: %s", keyword)
+	s.logger.DebugContext(ctx, "开始搜索用户", "keyword", keyword)
 
 	// 获取所有用户并过滤（简单实现）
-	allUsers, err := s.GetAllUsers()
+	allUsers, err := s.GetAllUsers(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -172,17 +176,17 @@ func (s *UserService) SearchUsers(keyword string) ([]User, error) {
 	}
 
 	duration := time.Since(start)
-	log.Printf("✅ [传统查询] 搜索完成，找到 %d 个匹配用户，耗时: %v", len(filteredUsers), duration)
+	s.logger.InfoContext(ctx, "搜索用户完成", "keyword", keyword, "count", len(filteredUsers), "duration", duration)
 
 	return filteredUsers, nil
 }
 
 // GetUserStats 获取用户统计 - 传统方法
-func (s *UserService) GetUserStats() (map[string]interface{}, error) {
+func (s *UserService) GetUserStats(ctx context.Context) (map[string]interface{}, error) {
 	start := time.Now()
-	log.Printf("🔍 [传统查询] 开始统计用户数据...")
+	s.logger.DebugContext(ctx, "开始统计用户数据")
 
-	users, err := s.GetAllUsers()
+	users, err := s.GetAllUsers(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -230,20 +234,20 @@ func (s *UserService) GetUserStats() (map[string]interface{}, error) {
 	}
 
 	duration := time.Since(start)
-	log.Printf("✅ [传统查询] 统计完成，总用户: %d，平均年龄: %.1f，平均薪资: %.2f，耗时: %v", totalUsers, avgAge, avgSalary, duration)
+	s.logger.InfoContext(ctx, "统计用户数据完成", "total_users", totalUsers, "duration", duration)
 
 	return stats, nil
 }
 
 // GetUserStatsWithTable 获取指定表的用户统计 - 传统方法
-func (s *UserService) GetUserStatsWithTable(tableName string) (map[string]interface{}, error) {
+func (s *UserService) GetUserStatsWithTable(ctx context.Context, tableName string) (map[string]interface{}, error) {
 	start := time.Now()
-	log.Printf("🔍 [传统查询] 开始统计用户数据，表: %s...", tableName)
+	s.logger.DebugContext(ctx, "开始统计用户数据", "table", tableName)
 
 	// 直接调用数据库查询指定表
-	data, err := s.mysqlClient.QueryUser(tableName)
+	data, err := s.mysqlClient.QueryUser(ctx, tableName)
 	if err != nil {
-		log.Printf("❌ [传统查询] 查询失败: %v", err)
+		s.logger.ErrorContext(ctx, "查询用户失败", "table", tableName, "error", err)
 		return nil, fmt.Errorf("查询用户失败: %v", err)
 	}
 
@@ -312,7 +316,7 @@ func (s *UserService) GetUserStatsWithTable(tableName string) (map[string]interf
 	}
 
 	duration := time.Since(start)
-	log.Printf("✅ [传统查询] 统计完成，表: %s，总用户: %d，平均年龄: %.1f，平均薪资: %.2f，耗时: %v", tableName, totalUsers, avgAge, avgSalary, duration)
+	s.logger.InfoContext(ctx, "统计用户数据完成", "table", tableName, "total_users", totalUsers, "duration", duration)
 
 	return stats, nil
 }
@@ -323,8 +327,8 @@ func contains(str, substr string) bool {
 }
 
 // 性能测试方法
-func (s *UserService) BenchmarkQuery(iterations int) map[string]interface{} {
-	log.Printf("🚀 [性能测试] 开始传统查询性能测试，迭代次数: %d", iterations)
+func (s *UserService) BenchmarkQuery(ctx context.Context, iterations int) map[string]interface{} {
+	s.logger.InfoContext(ctx, "开始传统查询性能测试", "iterations", iterations)
 
 	start := time.Now()
 	var totalDuration time.Duration
@@ -332,7 +336,7 @@ func (s *UserService) BenchmarkQuery(iterations int) map[string]interface{} {
 
 	for i := 0; i < iterations; i++ {
 		iterStart := time.Now()
-		_, err := s.GetAllUsers()
+		_, err := s.GetAllUsers(ctx)
 		iterDuration := time.Since(iterStart)
 		totalDuration += iterDuration
 
@@ -341,7 +345,7 @@ func (s *UserService) BenchmarkQuery(iterations int) map[string]interface{} {
 		}
 
 		if i%10 == 0 {
-			log.Printf("📊 [性能测试] 已完成 %d/%d 次查询", i+1, iterations)
+			s.logger.DebugContext(ctx, "性能测试进度", "completed", i+1, "iterations", iterations)
 		}
 	}
 
@@ -358,9 +362,8 @@ func (s *UserService) BenchmarkQuery(iterations int) map[string]interface{} {
 		"success_rate":     float64(successCount) / float64(iterations) * 100,
 	}
 
-	log.Printf("✅ [性能测试] 传统查询测试完成")
-	log.Printf("📈 总耗时: %v, 平均耗时: %v, QPS: %.2f",
-		totalTime, avgDuration, result["queries_per_sec"])
+	s.logger.InfoContext(ctx, "传统查询性能测试完成",
+		"total_time", totalTime, "average_time", avgDuration, "qps", result["queries_per_sec"])
 
 	return result
 }
@@ -0,0 +1,138 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+// 导出方式
+const (
+	ExporterNone   = "none"
+	ExporterStdout = "stdout"
+	ExporterOTLP   = "otlp"
+)
+
+// OTLP 协议
+const (
+	ProtocolGRPC = "grpc"
+	ProtocolHTTP = "http"
+)
+
+// DefaultServiceName 未配置 service_name 时上报的服务名
+const DefaultServiceName = "mcp-ai-client"
+
+// Config 链路追踪配置
+type Config struct {
+	Exporter    string     `yaml:"exporter"`     // none（默认）、stdout 或 otlp
+	ServiceName string     `yaml:"service_name"` // 默认 mcp-ai-client
+	SampleRatio float64    `yaml:"sample_ratio"` // 根span的采样比例(0,1]，默认1；有上游 traceparent 时跟随上游的采样决定
+	OTLP        OTLPConfig `yaml:"otlp"`
+}
+
+// OTLPConfig OTLP 导出配置
+type OTLPConfig struct {
+	Endpoint string            `yaml:"endpoint"` // host:port，例如 localhost:4317（grpc）或 localhost:4318（http）
+	Protocol string            `yaml:"protocol"` // grpc（默认）或 http
+	Insecure bool              `yaml:"insecure"` // 不使用 TLS
+	Headers  map[string]string `yaml:"headers"`  // 附带的请求头，例如鉴权
+}
+
+// WithDefaults 填充可省略的配置
+func (c Config) WithDefaults() Config {
+	if c.Exporter == "" {
+		c.Exporter = ExporterNone
+	}
+	if c.ServiceName == "" {
+		c.ServiceName = DefaultServiceName
+	}
+	if c.SampleRatio == 0 {
+		c.SampleRatio = 1
+	}
+	if c.OTLP.Protocol == "" {
+		c.OTLP.Protocol = ProtocolGRPC
+	}
+	return c
+}
+
+// Enabled 是否启用链路追踪
+func (c Config) Enabled() bool {
+	return c.Exporter != "" && c.Exporter != ExporterNone
+}
+
+// Setup 按配置创建 TracerProvider 并设为全局，同时启用 W3C trace context 传播
+// 返回的函数在退出前调用，用于导出缓冲中的span；未启用时不做任何设置
+func Setup(ctx context.Context, config Config, version string) (func(context.Context) error, error) {
+	config = config.WithDefaults()
+	if !config.Enabled() {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := newExporter(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL,
+		semconv.ServiceName(config.ServiceName),
+		semconv.ServiceVersion(version),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("创建追踪资源失败: %v", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(config.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	return provider.Shutdown, nil
+}
+
+// newExporter 创建span导出器
+func newExporter(ctx context.Context, config Config) (sdktrace.SpanExporter, error) {
+	switch config.Exporter {
+	case ExporterStdout:
+		return stdouttrace.New(stdouttrace.WithWriter(os.Stdout), stdouttrace.WithPrettyPrint())
+	case ExporterOTLP:
+		var client otlptrace.Client
+		if config.OTLP.Protocol == ProtocolHTTP {
+			opts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(config.OTLP.Endpoint)}
+			if config.OTLP.Insecure {
+				opts = append(opts, otlptracehttp.WithInsecure())
+			}
+			if len(config.OTLP.Headers) > 0 {
+				opts = append(opts, otlptracehttp.WithHeaders(config.OTLP.Headers))
+			}
+			client = otlptracehttp.NewClient(opts...)
+		} else {
+			opts := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(config.OTLP.Endpoint)}
+			if config.OTLP.Insecure {
+				opts = append(opts, otlptracegrpc.WithInsecure())
+			}
+			if len(config.OTLP.Headers) > 0 {
+				opts = append(opts, otlptracegrpc.WithHeaders(config.OTLP.Headers))
+			}
+			client = otlptracegrpc.NewClient(opts...)
+		}
+		exporter, err := otlptrace.New(ctx, client)
+		if err != nil {
+			return nil, fmt.Errorf("创建OTLP导出器失败: %v", err)
+		}
+		return exporter, nil
+	default:
+		return nil, fmt.Errorf("未知的追踪导出方式: %s", config.Exporter)
+	}
+}